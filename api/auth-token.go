@@ -1,9 +1,11 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"ocs-ad-inventorymanagement/client"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -57,3 +59,35 @@ func AuthTokenHandler(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, AuthTokenResponse{Token: tokenString})
 }
+
+// authenticateRequest memvalidasi header Authorization (Bearer JWT) dan mengembalikan username.
+// Jika tidak valid, response 401 sudah dikirim dan ok bernilai false.
+func authenticateRequest(c *gin.Context) (username string, ok bool) {
+	authHeader := c.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header (Bearer <token>) wajib"})
+		return "", false
+	}
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method")
+		}
+		return []byte(getJWTSecret()), nil
+	})
+	if err != nil || !token.Valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token tidak valid"})
+		return "", false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token tidak valid"})
+		return "", false
+	}
+	username, _ = claims["username"].(string)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token tidak valid (no username)"})
+		return "", false
+	}
+	return username, true
+}
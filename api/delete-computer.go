@@ -2,160 +2,92 @@ package api
 
 import (
 	"fmt"
+	"log"
 	"net/http"
-	"os"
-	"regexp"
 	"strings"
 
+	"ocs-ad-inventorymanagement/client"
+
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
 )
 
-// DeleteComputerHandler handles GET /delete-computer?name=xxx
-// Versi ini tetap menggunakan introspeksi skema namun dengan eksekusi query yang lebih aman.
-// Serve frontend if not API call, else process API
+// maxDeleteCandidates adalah jumlah kandidat maksimum yang dikembalikan saat nama ambigu
+const maxDeleteCandidates = 50
+
 // DeleteComputerHandler handles POST /delete-computer (API only, JSON input, JWT required)
+// Body berisi minimal salah satu dari {"id": 123, "deviceid": "...", "name": "..."}; semua field yang diisi harus cocok.
+// Jika lebih dari satu hardware cocok, tidak ada yang dihapus dan response 409 berisi daftar kandidat.
 func DeleteComputerHandler(db *gorm.DB) gin.HandlerFunc {
-	// Regex untuk validasi nama tabel tetap dipertahankan sebagai lapisan pertahanan tambahan (defense-in-depth).
-	var validTableName = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
-
-	// Whitelist nama tabel yang diizinkan
-	var allowedTables = map[string]struct{}{
-		"accesslog": {}, "accountinfo": {}, "accountinfo_config": {}, "archive": {}, "assets_categories": {}, "auth_attempt": {}, "batteries": {}, "bios": {}, "blacklist_macaddresses": {}, "blacklist_serials": {}, "blacklist_subnet": {}, "config": {}, "config_ldap": {}, "conntrack": {}, "controllers": {}, "cpus": {}, "cve_search": {}, "cve_search_computer": {}, "cve_search_correspondance": {}, "cve_search_history": {}, "deleted_equiv": {}, "deploy": {}, "devices": {}, "devicetype": {}, "dico_ignored": {}, "dico_soft": {}, "download_affect_rules": {}, "download_available": {}, "download_enable": {}, "download_history": {}, "download_servers": {}, "downloadwk_conf_values": {}, "downloadwk_fields": {}, "downloadwk_history": {}, "downloadwk_pack": {}, "downloadwk_statut_request": {}, "downloadwk_tab_values": {}, "drives": {}, "engine_mutex": {}, "engine_persistent": {}, "extensions": {}, "files": {}, "groups": {}, "groups_cache": {}, "hardware": {}, "hardware_osname_cache": {}, "history": {}, "inputs": {}, "itmgmt_comments": {}, "javainfo": {}, "journallog": {}, "languages": {}, "layouts": {}, "local_groups": {}, "local_users": {}, "locks": {}, "memories": {}, "modems": {}, "monitors": {}, "netmap": {}, "network_devices": {}, "networks": {}, "notification": {}, "notification_config": {}, "ports": {}, "printers": {}, "prolog_conntrack": {}, "regconfig": {}, "registry": {}, "registry_name_cache": {}, "registry_regvalue_cache": {}, "reports_notifications": {}, "repository": {}, "saas": {}, "saas_exp": {}, "save_query": {}, "schedule_wol": {}, "sim": {}, "slots": {}, "snmp_accountinfo": {}, "snmp_communities": {}, "snmp_configs": {}, "snmp_default": {}, "snmp_labels": {}, "snmp_mibs": {}, "snmp_ocs": {}, "snmp_types": {}, "snmp_types_conditions": {}, "software": {}, "software_categories": {}, "software_categories_link": {}, "software_category_exp": {}, "software_link": {}, "software_name": {}, "software_publisher": {}, "software_version": {}, "softwares_name_cache": {}, "sounds": {}, "ssl_store": {}, "storages": {}, "subnet": {}, "tags": {}, "temp_files": {}, "usbdevices": {}, "videos": {}, "virtualmachines": {},
-	}
-
 	return func(c *gin.Context) {
 		// --- JWT Auth ---
-		authHeader := c.GetHeader("Authorization")
-		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header (Bearer <token>) wajib"})
-			return
-		}
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		jwtSecret := os.Getenv("JWT_SECRET")
-		if jwtSecret == "" {
-			jwtSecret = "supersecretjwtkey"
-		}
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method")
-			}
-			return []byte(jwtSecret), nil
-		})
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token tidak valid"})
-			return
-		}
-		claims, ok := token.Claims.(jwt.MapClaims)
+		username, ok := authenticateRequest(c)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token tidak valid"})
-			return
-		}
-		username, _ := claims["username"].(string)
-		if username == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token tidak valid (no username)"})
 			return
 		}
 		// Parse JSON body
-		var req struct {
-			Name string `json:"name"`
-		}
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON, harus ada field 'name'"})
+		var sel client.HardwareSelector
+		if err := c.ShouldBindJSON(&sel); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON, harus ada field 'id', 'deviceid' atau 'name'"})
 			return
 		}
-		name := req.Name
-		if name == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "parameter 'name' wajib diisi"})
+		sel.DeviceID = strings.TrimSpace(sel.DeviceID)
+		sel.Name = strings.TrimSpace(sel.Name)
+		if sel.Empty() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "parameter 'id', 'deviceid' atau 'name' wajib diisi"})
 			return
 		}
 
-		// Struct sementara untuk menampung hasil query ID
-		var hardware struct {
-			ID int
-		}
+		// Semua query mengikuti context request; shutdown server menunggu handler ini selesai
+		db := db.WithContext(c.Request.Context())
 
-		// Cari id hardware berdasarkan nama.
-		if err := db.Table("hardware").Select("id").Where("name = ?", name).First(&hardware).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Computer Not Found"})
-				return
-			}
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Gagal mencari hardware: %v", err)})
+		// Ambil satu lebih dari batas agar tahu jika kandidat terpotong
+		candidates, err := client.FindHardware(db, sel, maxDeleteCandidates+1)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-
-		hwID := hardware.ID
-
-		// Ambil daftar tabel yang punya kolom HARDWARE_ID di skema saat ini (logika ini dipertahankan sesuai permintaan).
-		type tableRow struct {
-			TableName string `gorm:"column:TABLE_NAME"`
-		}
-		var tables []tableRow
-		query := `
-			SELECT DISTINCT TABLE_NAME
-			FROM information_schema.COLUMNS
-			WHERE TABLE_SCHEMA = DATABASE() AND COLUMN_NAME = 'HARDWARE_ID'
-		`
-		if err := db.Raw(query).Scan(&tables).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Gagal mengambil daftar tabel: %v", err)})
+		switch {
+		case len(candidates) == 0:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Computer Not Found"})
 			return
-		}
-
-		// Mulai transaksi
-		tx := db.Begin()
-		if tx.Error != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Gagal memulai transaksi: %v", tx.Error)})
+		case len(candidates) > 1:
+			truncated := len(candidates) > maxDeleteCandidates
+			if truncated {
+				candidates = candidates[:maxDeleteCandidates]
+			}
+			c.JSON(http.StatusConflict, gin.H{
+				"error":      "Ditemukan lebih dari satu hardware yang cocok, pilih salah satu berdasarkan 'id'",
+				"candidates": candidates,
+				"truncated":  truncated,
+			})
 			return
 		}
 
-		// Defer a rollback in case of panic
-		defer func() {
-			if r := recover(); r != nil {
-				tx.Rollback()
-			}
-		}()
-
-		for _, t := range tables {
-			tableName := t.TableName
-
-			// Lakukan validasi nama tabel sebagai lapisan keamanan tambahan.
-			if !validTableName.MatchString(tableName) {
-				// skip tabel yang namanya tidak valid untuk mencegah hal tak terduga.
-				continue
-			}
-			// Validasi whitelist nama tabel
-			if _, ok := allowedTables[tableName]; !ok {
-				// skip tabel yang tidak ada di whitelist
-				continue
-			}
-
-			// --- PERUBAHAN UTAMA ADA DI SINI ---
-			// Ganti Sprintf dengan metode GORM yang aman untuk nama tabel dinamis.
-			// GORM akan menangani quoting (misal: `nama_tabel`) secara otomatis dan aman.
-			if err := tx.Table(tableName).Where("HARDWARE_ID = ?", hwID).Delete(nil).Error; err != nil {
-				tx.Rollback()
-				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Gagal menghapus dari tabel %s: %v", tableName, err)})
-				return
-			}
-		}
+		target := candidates[0]
+		hwID := target.ID
 
-		// Hapus record hardware itu sendiri
-		if err := tx.Table("hardware").Where("id = ?", hwID).Delete(nil).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Gagal menghapus hardware: %v", err)})
+		// Ambil daftar tabel yang punya kolom HARDWARE_ID di skema saat ini
+		tables, err := client.HardwareTables(db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		// Commit transaksi
-		if err := tx.Commit().Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Gagal commit transaksi: %v", err)})
+		// Hapus semua baris terkait lalu record hardware itu sendiri dalam satu transaksi
+		err = db.Transaction(func(tx *gorm.DB) error {
+			_, err := client.DeleteHardwareRows(tx, tables, []int64{hwID})
+			return err
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
+		log.Printf("[INFO] OCS - %s menghapus hardware ID %d (%s, %s)", username, hwID, target.Name, target.DeviceID)
 		c.JSON(http.StatusOK, gin.H{
 			"message":    fmt.Sprintf("Semua data yang terkait dengan computer ID %d telah berhasil dihapus.", hwID),
+			"deleted":    target,
 			"deleted_by": username,
 		})
 
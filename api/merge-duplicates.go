@@ -0,0 +1,59 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"ocs-ad-inventorymanagement/client"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MergeDuplicatesHandler handles POST /ocs/merge-duplicates (JSON input, JWT required)
+// Body: {"dry_run": true, "match_by": ["name", "serial", "mac"]}.
+// dry_run default true: tanpa "dry_run": false tidak ada data yang dihapus.
+// Grup yang tersambung lintas kriteria hanya dilaporkan di result.review dan tidak pernah dihapus.
+func MergeDuplicatesHandler(db *gorm.DB, auditFile string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username, ok := authenticateRequest(c)
+		if !ok {
+			return
+		}
+		var req struct {
+			DryRun  *bool    `json:"dry_run"`
+			MatchBy []string `json:"match_by"`
+		}
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+				return
+			}
+		}
+		matchBy, err := client.ParseMatchBy(req.MatchBy)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		dryRun := req.DryRun == nil || *req.DryRun
+
+		result, err := client.MergeHardwareDuplicates(db.WithContext(c.Request.Context()), client.MergeOptions{
+			MatchBy:   matchBy,
+			DryRun:    dryRun,
+			Actor:     username,
+			AuditFile: auditFile,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
+			return
+		}
+		if !dryRun {
+			log.Printf("[INFO] OCS - %s menggabungkan %d grup duplikat, %d hardware dihapus, %d grup perlu ditinjau",
+				username, len(result.Groups), result.RemovedIDs, len(result.Review))
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"result":       result,
+			"requested_by": username,
+		})
+	}
+}
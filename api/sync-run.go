@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"ocs-ad-inventorymanagement/sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SyncTrigger adalah scheduler yang dapat menjalankan siklus sinkronisasi on-demand
+type SyncTrigger interface {
+	RunNow(ctx context.Context) (sync.Report, error)
+}
+
+// SyncStatusProvider adalah scheduler yang dapat melaporkan kondisinya
+type SyncStatusProvider interface {
+	Status() sync.Status
+}
+
+// SyncRunHandler handles POST /sync/run (JWT required)
+// Memicu siklus sinkronisasi segera. Jika siklus sedang berjalan, satu siklus baru
+// dijalankan setelahnya dan request menunggu report siklus baru tersebut.
+func SyncRunHandler(trigger SyncTrigger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username, ok := authenticateRequest(c)
+		if !ok {
+			return
+		}
+
+		report, err := trigger.RunNow(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":        err.Error(),
+				"report":       report,
+				"triggered_by": username,
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"report":       report,
+			"triggered_by": username,
+		})
+	}
+}
+
+// SyncStatusHandler handles GET /sync/status
+// Mengembalikan kondisi scheduler; status HTTP 503 jika sinkronisasi sedang degraded.
+func SyncStatusHandler(provider SyncStatusProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := provider.Status()
+		code := http.StatusOK
+		if status.State == "degraded" {
+			code = http.StatusServiceUnavailable
+		}
+		c.JSON(code, status)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"ocs-ad-inventorymanagement/client"
+)
+
+// runCommand menjalankan subcommand CLI dan mengembalikan exit code.
+// Tanpa argumen aplikasi berjalan sebagai service (web API + scheduler).
+func runCommand(args []string) int {
+	switch args[0] {
+	case "merge-ocs-duplicates":
+		return mergeOCSDuplicatesCommand(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Perintah tidak dikenal: %s\n\nPerintah yang tersedia:\n  merge-ocs-duplicates  gabungkan hardware OCS duplikat (nama/serial/MAC sama)\n", args[0])
+		return 2
+	}
+}
+
+// mergeOCSDuplicatesCommand: merge-ocs-duplicates [-dry-run=false] [-match name,serial,mac] [-json]
+func mergeOCSDuplicatesCommand(args []string) int {
+	fs := flag.NewFlagSet("merge-ocs-duplicates", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", true, "hanya tampilkan yang akan dihapus; -dry-run=false untuk benar-benar menghapus")
+	match := fs.String("match", strings.Join(client.DefaultMatchBy, ","), "kriteria duplikat, dipisah koma: name, serial, mac")
+	asJSON := fs.Bool("json", false, "cetak hasil sebagai JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	matchBy, err := client.ParseMatchBy([]string{*match})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	ocsCfg := client.LoadOCSConfig()
+	ocsClient, err := client.NewOCSMySQLClient(ocsCfg)
+	if err != nil {
+		log.Printf("[FATAL] OCS - Koneksi gagal: %v", err)
+		return 1
+	}
+
+	actor := "cli"
+	if u := os.Getenv("USER"); u != "" {
+		actor = "cli:" + u
+	}
+	result, err := client.MergeHardwareDuplicates(ocsClient.DB, client.MergeOptions{
+		MatchBy:   matchBy,
+		DryRun:    *dryRun,
+		Actor:     actor,
+		AuditFile: ocsCfg.MergeAuditFile,
+	})
+	if err != nil {
+		log.Printf("[ERROR] OCS - Merge duplikat gagal: %v", err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(result)
+		return 0
+	}
+	printMergeResult(result, ocsCfg.MergeAuditFile)
+	return 0
+}
+
+// printMergeResult mencetak ringkasan merge per grup, grup yang perlu ditinjau dan total per tabel
+func printMergeResult(result client.MergeResult, auditFile string) {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.Format("2006-01-02 15:04:05")
+	}
+	if len(result.Review) > 0 {
+		fmt.Printf("Perlu ditinjau manual (tersambung lintas kriteria, tidak dihapus): %d grup\n", len(result.Review))
+		for _, g := range result.Review {
+			fmt.Printf("  ID %d %s (LASTDATE %s) - cocok: %s\n",
+				g.Keep.ID, g.Keep.Name, formatTime(g.Keep.LastDate), strings.Join(g.MatchedBy, ", "))
+			for _, r := range g.Remove {
+				fmt.Printf("    ID %d %s (LASTDATE %s)\n", r.ID, r.Name, formatTime(r.LastDate))
+			}
+		}
+		fmt.Println()
+	}
+	if len(result.Groups) == 0 {
+		fmt.Printf("Tidak ada hardware duplikat yang dapat digabung otomatis (kriteria: %s).\n", strings.Join(result.MatchBy, ", "))
+		return
+	}
+	for _, g := range result.Groups {
+		fmt.Printf("Pertahankan ID %d %s (LASTDATE %s) - cocok: %s\n",
+			g.Keep.ID, g.Keep.Name, formatTime(g.Keep.LastDate), strings.Join(g.MatchedBy, ", "))
+		for _, r := range g.Remove {
+			fmt.Printf("  hapus ID %d %s (LASTDATE %s)\n", r.ID, r.Name, formatTime(r.LastDate))
+		}
+	}
+
+	tables := make([]string, 0, len(result.Tables))
+	for t := range result.Tables {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	verb := "Dihapus"
+	if result.DryRun {
+		verb = "Akan dihapus"
+	}
+	fmt.Printf("\n%s: %d hardware dari %d grup\n", verb, result.RemovedIDs, len(result.Groups))
+	for _, t := range tables {
+		fmt.Printf("  %-28s %d baris\n", t, result.Tables[t])
+	}
+	if result.DryRun {
+		fmt.Println("\nDry-run: tidak ada data yang dihapus. Jalankan dengan -dry-run=false untuk menghapus.")
+	} else {
+		fmt.Printf("\nAudit dicatat di %s\n", auditFile)
+	}
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LastLogon adalah nilai lastLogon terbaru sebuah komputer beserta DC asalnya
+type LastLogon struct {
+	Value  int64  // FILETIME (100ns sejak 1601-01-01)
+	Server string // Host DC yang menyimpan nilai ini
+}
+
+// uacServerTrustAccount menandai akun komputer domain controller
+const uacServerTrustAccount = 8192
+
+// DomainControllers mengembalikan daftar DC untuk query lastLogon.
+// Jika beberapa DC dikonfigurasi (LDAP_URLS/LDAP_SRV_DOMAIN), daftar tersebut yang dipakai;
+// jika hanya satu, DC lain ditemukan lewat AD (akun komputer dengan SERVER_TRUST_ACCOUNT)
+// di seluruh domain, bukan hanya di LDAP_SEARCH_BASE.
+func (c *LDAPClient) DomainControllers(ctx context.Context) ([]LDAPServer, error) {
+	servers, err := c.Config.Servers()
+	if err != nil {
+		return nil, err
+	}
+	if len(servers) > 1 {
+		return servers, nil
+	}
+
+	// DC berada di OU=Domain Controllers, biasanya di luar LDAP_SEARCH_BASE,
+	// sehingga pencarian dimulai dari defaultNamingContext domain
+	info, err := c.DSAInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menemukan domain controller: %v", err)
+	}
+	if info.DefaultNamingContext == "" {
+		return nil, fmt.Errorf("gagal menemukan domain controller: rootDSE tidak memiliki defaultNamingContext")
+	}
+
+	var hosts []string
+	filter := fmt.Sprintf("(&(objectCategory=computer)(userAccountControl:1.2.840.113556.1.4.803:=%d))", uacServerTrustAccount)
+	err = c.searchBaseFunc(ctx, info.DefaultNamingContext, filter, []string{"dNSHostName"}, nil, func(entry *ldap.Entry) error {
+		hosts = append(hosts, entry.GetAttributeValue("dNSHostName"))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gagal menemukan domain controller: %v", err)
+	}
+	discovered := discoveredDCs(c.Server, hosts)
+	if len(discovered) == 1 {
+		log.Printf("[WARN] LDAP - Hanya DC %s yang ditemukan di %s, lastLogon tidak dibandingkan dengan DC lain", c.Server.Host, info.DefaultNamingContext)
+	}
+	return discovered, nil
+}
+
+// discoveredDCs membangun daftar DC dari dNSHostName hasil pencarian: DC yang sedang terkoneksi
+// di urutan pertama, host kosong dan duplikat (tanpa membedakan huruf besar/kecil) dibuang.
+// Scheme dan port mengikuti DC yang sedang terkoneksi.
+func discoveredDCs(current LDAPServer, hosts []string) []LDAPServer {
+	seen := map[string]bool{strings.ToLower(current.Host): true}
+	discovered := []LDAPServer{current}
+	for _, host := range hosts {
+		if host == "" || seen[strings.ToLower(host)] {
+			continue
+		}
+		seen[strings.ToLower(host)] = true
+		discovered = append(discovered, LDAPServer{Scheme: current.Scheme, Host: host, Port: current.Port})
+	}
+	return discovered
+}
+
+// dcSearchFunc menjalankan pencarian lastLogon di satu DC dan memanggil fn untuk setiap entry
+type dcSearchFunc func(ctx context.Context, server LDAPServer, fn func(*ldap.Entry) error) error
+
+// LastLogonFromAllDCs mengambil lastLogon setiap komputer dari semua DC dan
+// mengembalikan nilai terbesar per DN (huruf kecil). DC yang gagal dilewati.
+func (c *LDAPClient) LastLogonFromAllDCs(ctx context.Context) (map[string]LastLogon, error) {
+	servers, err := c.DomainControllers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := c.Config.Filter
+	if filter == "" {
+		filter = "(objectClass=computer)"
+	}
+	return lastLogonFromDCs(ctx, servers, func(ctx context.Context, server LDAPServer, fn func(*ldap.Entry) error) error {
+		dc, err := connectLDAP(c.Config, server)
+		if err != nil {
+			return err
+		}
+		defer dc.Close()
+		return dc.SearchFunc(ctx, filter, []string{"lastLogon"}, fn)
+	})
+}
+
+// lastLogonFromDCs menjalankan search di setiap DC dan menyimpan lastLogon terbesar per DN.
+// DC yang tidak terjangkau atau gagal di tengah pencarian dilewati (nilai yang sudah terbaca tetap
+// dipakai, karena tetap nilai nyata); error hanya dikembalikan jika tidak ada DC yang berhasil
+// atau ctx dibatalkan.
+func lastLogonFromDCs(ctx context.Context, servers []LDAPServer, search dcSearchFunc) (map[string]LastLogon, error) {
+	result := make(map[string]LastLogon)
+	queried := 0
+	for _, server := range servers {
+		err := search(ctx, server, func(entry *ldap.Entry) error {
+			v, err := strconv.ParseInt(entry.GetAttributeValue("lastLogon"), 10, 64)
+			if err != nil || v <= 0 {
+				return nil
+			}
+			dn := strings.ToLower(entry.DN)
+			if cur, ok := result[dn]; !ok || v > cur.Value {
+				result[dn] = LastLogon{Value: v, Server: server.Host}
+			}
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			log.Printf("[WARN] LDAP - Lewati DC %s untuk lastLogon: %v", server, err)
+			continue
+		}
+		queried++
+	}
+	if queried == 0 {
+		return nil, fmt.Errorf("tidak ada DC yang berhasil di-query untuk lastLogon")
+	}
+	log.Printf("[INFO] LDAP - lastLogon diambil dari %d/%d domain controller", queried, len(servers))
+	return result, nil
+}
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// fakeDC adalah hasil pencarian lastLogon satu DC: DN -> lastLogon, err dikembalikan setelah entries
+type fakeDC struct {
+	entries map[string]int64
+	err     error
+}
+
+// fakeDCSearch mengembalikan dcSearchFunc yang membaca hasil dari dcs berdasarkan host
+func fakeDCSearch(dcs map[string]fakeDC) dcSearchFunc {
+	return func(_ context.Context, server LDAPServer, fn func(*ldap.Entry) error) error {
+		dc := dcs[server.Host]
+		for dn, v := range dc.entries {
+			entry := ldap.NewEntry(dn, map[string][]string{"lastLogon": {strconv.FormatInt(v, 10)}})
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+		return dc.err
+	}
+}
+
+func TestLastLogonFromDCs(t *testing.T) {
+	servers := []LDAPServer{{Host: "dc1"}, {Host: "dc2"}, {Host: "dc3"}}
+	const (
+		pc1 = "CN=PC-01,OU=PC,DC=corp,DC=local"
+		pc2 = "CN=PC-02,OU=PC,DC=corp,DC=local"
+		pc3 = "cn=pc-03,ou=pc,dc=corp,dc=local"
+	)
+	unreachable := errors.New("dial tcp: connection refused")
+
+	for _, tc := range []struct {
+		name    string
+		dcs     map[string]fakeDC
+		want    map[string]LastLogon
+		wantErr bool
+	}{
+		{
+			name: "nilai terbesar per DN",
+			dcs: map[string]fakeDC{
+				"dc1": {entries: map[string]int64{pc1: 300, pc2: 100}},
+				"dc2": {entries: map[string]int64{pc1: 200, pc2: 500}},
+				"dc3": {entries: map[string]int64{pc1: 0, pc3: 50}},
+			},
+			want: map[string]LastLogon{
+				"cn=pc-01,ou=pc,dc=corp,dc=local": {Value: 300, Server: "dc1"},
+				"cn=pc-02,ou=pc,dc=corp,dc=local": {Value: 500, Server: "dc2"},
+				pc3:                               {Value: 50, Server: "dc3"},
+			},
+		},
+		{
+			name: "satu DC tidak terjangkau",
+			dcs: map[string]fakeDC{
+				"dc1": {entries: map[string]int64{pc1: 300}},
+				"dc2": {err: unreachable},
+				"dc3": {entries: map[string]int64{pc1: 400}},
+			},
+			want: map[string]LastLogon{
+				"cn=pc-01,ou=pc,dc=corp,dc=local": {Value: 400, Server: "dc3"},
+			},
+		},
+		{
+			name: "DC gagal di tengah pencarian",
+			dcs: map[string]fakeDC{
+				"dc1": {entries: map[string]int64{pc1: 300}},
+				"dc2": {entries: map[string]int64{pc2: 700}, err: errors.New("connection reset")},
+				"dc3": {err: unreachable},
+			},
+			want: map[string]LastLogon{
+				"cn=pc-01,ou=pc,dc=corp,dc=local": {Value: 300, Server: "dc1"},
+				"cn=pc-02,ou=pc,dc=corp,dc=local": {Value: 700, Server: "dc2"},
+			},
+		},
+		{
+			name: "semua DC gagal",
+			dcs: map[string]fakeDC{
+				"dc1": {err: unreachable},
+				"dc2": {err: unreachable},
+				"dc3": {err: unreachable},
+			},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := lastLogonFromDCs(context.Background(), servers, fakeDCSearch(tc.dcs))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("err = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("result = %v, want %v", got, tc.want)
+			}
+			for dn, want := range tc.want {
+				if got[dn] != want {
+					t.Errorf("%s = %+v, want %+v", dn, got[dn], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLastLogonFromDCsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	_, err := lastLogonFromDCs(ctx, []LDAPServer{{Host: "dc1"}, {Host: "dc2"}}, func(ctx context.Context, _ LDAPServer, _ func(*ldap.Entry) error) error {
+		calls++
+		cancel()
+		return ctx.Err()
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("err = %v, calls = %d; want error setelah DC pertama", err, calls)
+	}
+}
+
+func TestDiscoveredDCs(t *testing.T) {
+	current := LDAPServer{Scheme: "ldaps", Host: "dc1.corp.local", Port: 636}
+	got := discoveredDCs(current, []string{"DC1.corp.local", "", "dc2.corp.local", "DC2.CORP.LOCAL", "dc3.corp.local"})
+	want := []LDAPServer{
+		current,
+		{Scheme: "ldaps", Host: "dc2.corp.local", Port: 636},
+		{Scheme: "ldaps", Host: "dc3.corp.local", Port: 636},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("servers = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("servers[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// LDAPServer adalah satu domain controller tujuan koneksi
+type LDAPServer struct {
+	Scheme string // "ldap" atau "ldaps"
+	Host   string
+	Port   int
+}
+
+// URL mengembalikan alamat server dalam bentuk ldap(s)://host:port
+func (s LDAPServer) URL() string {
+	return fmt.Sprintf("%s://%s", s.Scheme, net.JoinHostPort(s.Host, strconv.Itoa(s.Port)))
+}
+
+func (s LDAPServer) String() string {
+	return s.URL()
+}
+
+// serverCounter menyimpan posisi round-robin antar koneksi baru
+var serverCounter uint32
+
+// nextServerIndex mengembalikan indeks server awal untuk koneksi berikutnya
+func nextServerIndex(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	return int((atomic.AddUint32(&serverCounter, 1) - 1) % uint32(n))
+}
+
+// defaultScheme menentukan skema URL dari mode TLS
+func (cfg LDAPConfig) defaultScheme() string {
+	if cfg.TLSMode == LDAPTLSLDAPS {
+		return "ldaps"
+	}
+	return "ldap"
+}
+
+// Servers mengembalikan daftar domain controller dengan urutan prioritas:
+// LDAP_URLS, lalu DNS SRV _ldap._tcp.<LDAP_SRV_DOMAIN>, lalu LDAP_HOST/LDAP_PORT.
+func (cfg LDAPConfig) Servers() ([]LDAPServer, error) {
+	if len(cfg.URLs) > 0 {
+		var servers []LDAPServer
+		for _, raw := range cfg.URLs {
+			s, err := parseLDAPURL(raw, cfg.defaultScheme())
+			if err != nil {
+				return nil, err
+			}
+			servers = append(servers, s)
+		}
+		return servers, nil
+	}
+
+	if cfg.SRVDomain != "" {
+		return cfg.lookupSRV()
+	}
+
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("LDAP_HOST, LDAP_URLS atau LDAP_SRV_DOMAIN wajib diisi")
+	}
+	return []LDAPServer{{Scheme: cfg.defaultScheme(), Host: cfg.Host, Port: cfg.Port}}, nil
+}
+
+// lookupSRV menemukan domain controller lewat record DNS SRV _ldap._tcp.<domain>.
+// Record sudah diurutkan berdasarkan priority dan weight oleh net.LookupSRV.
+func (cfg LDAPConfig) lookupSRV() ([]LDAPServer, error) {
+	_, records, err := net.LookupSRV("ldap", "tcp", cfg.SRVDomain)
+	if err != nil {
+		return nil, fmt.Errorf("gagal lookup DNS SRV _ldap._tcp.%s: %v", cfg.SRVDomain, err)
+	}
+	scheme := cfg.defaultScheme()
+	var servers []LDAPServer
+	for _, r := range records {
+		port := int(r.Port)
+		// SRV _ldap._tcp selalu menunjuk port 389, gunakan port LDAPS untuk mode ldaps
+		if scheme == "ldaps" {
+			port = cfg.Port
+		}
+		servers = append(servers, LDAPServer{
+			Scheme: scheme,
+			Host:   strings.TrimSuffix(r.Target, "."),
+			Port:   port,
+		})
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("tidak ada record DNS SRV untuk _ldap._tcp.%s", cfg.SRVDomain)
+	}
+	return servers, nil
+}
+
+// parseLDAPURL mem-parsing "ldap://host:port", "ldaps://host" atau "host:port"
+func parseLDAPURL(raw, defaultScheme string) (LDAPServer, error) {
+	if !strings.Contains(raw, "://") {
+		raw = defaultScheme + "://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return LDAPServer{}, fmt.Errorf("URL LDAP tidak valid %q: %v", raw, err)
+	}
+	s := LDAPServer{Scheme: strings.ToLower(u.Scheme), Host: u.Hostname()}
+	if s.Scheme != "ldap" && s.Scheme != "ldaps" {
+		return LDAPServer{}, fmt.Errorf("skema URL LDAP %q tidak didukung", u.Scheme)
+	}
+	if s.Host == "" {
+		return LDAPServer{}, fmt.Errorf("URL LDAP %q tidak memiliki host", raw)
+	}
+	s.Port = 389
+	if s.Scheme == "ldaps" {
+		s.Port = 636
+	}
+	if p := u.Port(); p != "" {
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			return LDAPServer{}, fmt.Errorf("port URL LDAP %q tidak valid", raw)
+		}
+		s.Port = port
+	}
+	return s, nil
+}
+
+// splitList memecah daftar dipisahkan koma dan membuang item kosong
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// DSAInfo berisi informasi rootDSE domain controller yang dipakai untuk sinkronisasi incremental
+type DSAInfo struct {
+	HighestCommittedUSN  int64
+	InvocationID         string // Berubah jika database DC di-restore, USN lama menjadi tidak valid
+	DefaultNamingContext string
+}
+
+// DSAInfo membaca highestCommittedUSN dan invocationId dari DC yang sedang terkoneksi
+func (c *LDAPClient) DSAInfo(ctx context.Context) (DSAInfo, error) {
+	var info DSAInfo
+	root, err := c.searchOne(ctx, "", []string{"highestCommittedUSN", "dsServiceName", "defaultNamingContext"})
+	if err != nil {
+		return info, fmt.Errorf("gagal membaca rootDSE: %v", err)
+	}
+	info.HighestCommittedUSN, err = strconv.ParseInt(root.GetAttributeValue("highestCommittedUSN"), 10, 64)
+	if err != nil {
+		return info, fmt.Errorf("highestCommittedUSN tidak valid: %v", err)
+	}
+	info.DefaultNamingContext = root.GetAttributeValue("defaultNamingContext")
+
+	dsService := root.GetAttributeValue("dsServiceName")
+	if dsService == "" {
+		return info, fmt.Errorf("rootDSE tidak memiliki dsServiceName")
+	}
+	ntds, err := c.searchOne(ctx, dsService, []string{"invocationId"})
+	if err != nil {
+		return info, fmt.Errorf("gagal membaca invocationId: %v", err)
+	}
+	info.InvocationID = hex.EncodeToString(ntds.GetRawAttributeValue("invocationId"))
+	return info, nil
+}
+
+// searchOne membaca satu objek (scope base) pada DN tertentu
+func (c *LDAPClient) searchOne(ctx context.Context, dn string, attributes []string) (*ldap.Entry, error) {
+	req := ldap.NewSearchRequest(
+		dn,
+		ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 0, false,
+		"(objectClass=*)",
+		attributes,
+		nil,
+	)
+	var entry *ldap.Entry
+	res := c.Conn.SearchAsync(ctx, req, 0)
+	for res.Next() {
+		if e := res.Entry(); e != nil && entry == nil {
+			entry = e
+		}
+	}
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("objek %q tidak ditemukan", dn)
+	}
+	return entry, nil
+}
+
+// SearchDeletedFunc mencari objek di container Deleted Objects (memakai control show deleted).
+// Dipakai untuk mendeteksi komputer yang dihapus sejak sinkronisasi terakhir.
+func (c *LDAPClient) SearchDeletedFunc(ctx context.Context, namingContext, filter string, attributes []string, fn func(*ldap.Entry) error) error {
+	base := "CN=Deleted Objects," + namingContext
+	return c.searchBaseFunc(ctx, base, filter, attributes, []ldap.Control{ldap.NewControlMicrosoftShowDeleted()}, fn)
+}
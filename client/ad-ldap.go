@@ -1,35 +1,175 @@
 package client
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/go-ldap/ldap/v3"
 )
 
 // LDAPConfig menyimpan konfigurasi koneksi ke server LDAP (Active Directory)
 type LDAPConfig struct {
+	Domain     string // Label domain sumber AD, kosong untuk konfigurasi tunggal
 	Host       string
 	Port       int
 	BindDN     string
 	BindPass   string
 	SearchBase string
+	Filter     string   // Filter pencarian komputer, default "(objectClass=computer)"
+	Attributes []string // Atribut yang diminta, default DefaultLDAPAttributes
+	PageSize   int      // Ukuran halaman paged search, harus <= MaxPageSize AD (default 1000)
+
+	// Beberapa domain controller untuk failover. Jika kosong, dipakai Host/Port.
+	URLs      []string // LDAP_URLS, mis. "ldaps://dc1.corp.local,ldaps://dc2.corp.local"
+	SRVDomain string   // LDAP_SRV_DOMAIN, temukan DC lewat DNS SRV _ldap._tcp.<domain>
+
+	// LastLogonAllDCs mengambil lastLogon dari semua DC dan memakai nilai terbaru,
+	// karena atribut lastLogon tidak direplikasi antar DC.
+	LastLogonAllDCs bool
+
+	// Sinkronisasi incremental berdasarkan uSNChanged. lastLogon tidak dibaca dalam mode ini karena
+	// perubahannya tidak menaikkan uSNChanged; waktu login memakai lastLogonTimestamp, atau nilai
+	// terbaru dari semua DC yang dibaca ulang setiap siklus jika LastLogonAllDCs aktif.
+	Incremental        bool          // LDAP_INCREMENTAL
+	FullResyncInterval time.Duration // LDAP_FULL_RESYNC_INTERVAL, default 24 jam
+	StateDir           string        // LDAP_STATE_DIR, lokasi file high-water mark, default "data"
+
+	// Pengaturan TLS
+	TLSMode            string // "none", "starttls" atau "ldaps"
+	CAFile             string // CA bundle (PEM) untuk verifikasi sertifikat DC
+	ClientCertFile     string // Sertifikat client (PEM), opsional
+	ClientKeyFile      string // Private key client (PEM), opsional
+	ServerName         string // Override nama server untuk verifikasi sertifikat
+	InsecureSkipVerify bool
 }
 
+// DefaultLDAPAttributes adalah atribut komputer yang diminta jika LDAP_ATTRIBUTES tidak diisi
+var DefaultLDAPAttributes = []string{
+	"name", "operatingSystem", "operatingSystemVersion", "lastLogon", "lastLogonTimestamp", "whenChanged", "userAccountControl",
+	"dNSHostName", "distinguishedName", "description", "managedBy", "objectSid", "whenCreated", "pwdLastSet", "servicePrincipalName",
+}
+
+// Mode TLS yang didukung untuk koneksi LDAP
+const (
+	LDAPTLSNone     = "none"
+	LDAPTLSStartTLS = "starttls"
+	LDAPTLSLDAPS    = "ldaps"
+)
+
 // LoadLDAPConfig memuat konfigurasi LDAP dari environment variables
 func LoadLDAPConfig() LDAPConfig {
-	portStr := os.Getenv("LDAP_PORT")
+	return loadLDAPConfig("", "")
+}
+
+// LoadLDAPConfigs memuat semua sumber AD. Jika LDAP_SOURCES diisi (mis. "corp,lab"),
+// setiap sumber dibaca dari LDAP_<NAMA>_HOST, LDAP_<NAMA>_BIND_DN, LDAP_<NAMA>_SEARCH_BASE, dst.
+// Pengaturan TLS dan page size yang tidak diisi per sumber mengikuti nilai global LDAP_*.
+// Jika LDAP_SOURCES kosong, dipakai satu sumber dari LDAP_* tanpa label domain.
+func LoadLDAPConfigs() []LDAPConfig {
+	names := splitList(os.Getenv("LDAP_SOURCES"))
+	if len(names) == 0 {
+		return []LDAPConfig{LoadLDAPConfig()}
+	}
+	var configs []LDAPConfig
+	for _, name := range names {
+		prefix := "LDAP_" + strings.ToUpper(name) + "_"
+		domain := os.Getenv(prefix + "DOMAIN")
+		if domain == "" {
+			domain = strings.ToLower(name)
+		}
+		configs = append(configs, loadLDAPConfig(prefix, domain))
+	}
+	return configs
+}
+
+// loadLDAPConfig membaca konfigurasi satu sumber AD dengan prefix env tertentu
+func loadLDAPConfig(prefix, domain string) LDAPConfig {
+	// own: hanya dari prefix sumber ini (target koneksi dan akun bind)
+	own := func(key string) string {
+		if prefix == "" {
+			return os.Getenv("LDAP_" + key)
+		}
+		return os.Getenv(prefix + key)
+	}
+	// inherit: dari prefix sumber, jika kosong ikut nilai global LDAP_*
+	inherit := func(key string) string {
+		if v := own(key); v != "" {
+			return v
+		}
+		return os.Getenv("LDAP_" + key)
+	}
+
+	tlsMode := strings.ToLower(inherit("TLS_MODE"))
+	if tlsMode == "" {
+		tlsMode = LDAPTLSNone
+	}
+
+	portStr := own("PORT")
 	if portStr == "" {
 		portStr = "389" // Default LDAP port
+		if tlsMode == LDAPTLSLDAPS {
+			portStr = "636" // Default LDAPS port
+		}
 	}
 	port, _ := Atoi(portStr)
 
+	pageSize, err := Atoi(inherit("PAGE_SIZE"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 500
+	}
+
+	filter := own("FILTER")
+	if filter == "" {
+		filter = "(objectClass=computer)"
+	}
+
+	fullResync, err := time.ParseDuration(inherit("FULL_RESYNC_INTERVAL"))
+	if err != nil || fullResync <= 0 {
+		fullResync = 24 * time.Hour
+	}
+	stateDir := inherit("STATE_DIR")
+	if stateDir == "" {
+		stateDir = "data"
+	}
+
+	// Atribut "name" selalu diminta karena menjadi kunci penggabungan
+	attributes := splitList(inherit("ATTRIBUTES"))
+	if len(attributes) == 0 {
+		attributes = DefaultLDAPAttributes
+	} else if !containsFold(attributes, "name") {
+		attributes = append([]string{"name"}, attributes...)
+	}
+
 	return LDAPConfig{
-		Host:       os.Getenv("LDAP_HOST"),
+		Domain:     domain,
+		Host:       own("HOST"),
 		Port:       port,
-		BindDN:     os.Getenv("LDAP_BIND_DN"),
-		BindPass:   os.Getenv("LDAP_BIND_PASSWORD"),
-		SearchBase: os.Getenv("LDAP_SEARCH_BASE"),
+		BindDN:     own("BIND_DN"),
+		BindPass:   own("BIND_PASSWORD"),
+		SearchBase: own("SEARCH_BASE"),
+		Filter:     filter,
+		Attributes: attributes,
+		PageSize:   pageSize,
+		URLs:       splitList(own("URLS")),
+		SRVDomain:  own("SRV_DOMAIN"),
+
+		LastLogonAllDCs: inherit("LASTLOGON_ALL_DCS") == "true",
+
+		Incremental:        inherit("INCREMENTAL") == "true",
+		FullResyncInterval: fullResync,
+		StateDir:           stateDir,
+
+		TLSMode:            tlsMode,
+		CAFile:             inherit("CA_FILE"),
+		ClientCertFile:     inherit("CLIENT_CERT_FILE"),
+		ClientKeyFile:      inherit("CLIENT_KEY_FILE"),
+		ServerName:         own("TLS_SERVER_NAME"),
+		InsecureSkipVerify: inherit("TLS_INSECURE_SKIP_VERIFY") == "true",
 	}
 }
 
@@ -37,41 +177,202 @@ func LoadLDAPConfig() LDAPConfig {
 type LDAPClient struct {
 	Conn   *ldap.Conn
 	Config LDAPConfig
+	Server LDAPServer // Domain controller yang melayani koneksi ini
 }
 
-// NewLDAPClient membuat client baru dan melakukan koneksi serta bind ke server LDAP
-func NewLDAPClient(cfg LDAPConfig) (*LDAPClient, error) {
-	conn, err := ldap.Dial("tcp", fmt.Sprintf("%s:%d", cfg.Host, cfg.Port))
+// TLSConfig membangun konfigurasi TLS dari CA bundle, sertifikat client dan server name.
+// host dipakai sebagai server name jika LDAP_TLS_SERVER_NAME tidak diisi.
+func (cfg LDAPConfig) TLSConfig(host string) (*tls.Config, error) {
+	tc := &tls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if tc.ServerName == "" {
+		tc.ServerName = host
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("gagal membaca LDAP_CA_FILE: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("LDAP_CA_FILE %s tidak berisi sertifikat PEM yang valid", cfg.CAFile)
+		}
+		tc.RootCAs = pool
+	}
+	if cfg.ClientCertFile != "" || cfg.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("gagal memuat sertifikat client LDAP: %v", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return tc, nil
+}
+
+// dialLDAP membuka koneksi ke satu server LDAP sesuai skema URL dan mode TLS.
+// Koneksi tanpa enkripsi hanya dipakai jika LDAP_TLS_MODE=none.
+func dialLDAP(cfg LDAPConfig, server LDAPServer) (*ldap.Conn, error) {
+	switch {
+	case server.Scheme == "ldaps":
+		tc, err := cfg.TLSConfig(server.Host)
+		if err != nil {
+			return nil, err
+		}
+		return ldap.DialURL(server.URL(), ldap.DialWithTLSConfig(tc))
+	case cfg.TLSMode == LDAPTLSStartTLS || cfg.TLSMode == LDAPTLSLDAPS:
+		// Pada mode ldaps, entri ldap:// (mis. di LDAP_URLS) di-upgrade dengan StartTLS
+		// agar password akun bind tidak pernah dikirim tanpa enkripsi
+		tc, err := cfg.TLSConfig(server.Host)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := ldap.DialURL(server.URL())
+		if err != nil {
+			return nil, err
+		}
+		if err := conn.StartTLS(tc); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("StartTLS gagal: %v", err)
+		}
+		return conn, nil
+	case cfg.TLSMode == "" || cfg.TLSMode == LDAPTLSNone:
+		return ldap.DialURL(server.URL())
+	default:
+		return nil, fmt.Errorf("LDAP_TLS_MODE %q tidak dikenal (none/starttls/ldaps)", cfg.TLSMode)
+	}
+}
+
+// connectLDAP melakukan koneksi dan bind ke satu server LDAP
+func connectLDAP(cfg LDAPConfig, server LDAPServer) (*LDAPClient, error) {
+	conn, err := dialLDAP(cfg, server)
 	if err != nil {
-		return nil, fmt.Errorf("gagal koneksi ke server LDAP: %v", err)
+		return nil, fmt.Errorf("gagal koneksi ke server LDAP %s: %v", server, err)
 	}
 
 	err = conn.Bind(cfg.BindDN, cfg.BindPass)
 	if err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("gagal bind/autentikasi ke LDAP: %v", err)
+		return nil, fmt.Errorf("gagal bind/autentikasi ke LDAP %s: %v", server, err)
+	}
+
+	return &LDAPClient{Conn: conn, Config: cfg, Server: server}, nil
+}
+
+// NewLDAPClient membuat client baru dan melakukan koneksi serta bind ke server LDAP.
+// Jika beberapa DC dikonfigurasi, DC dicoba bergiliran (round-robin) hingga ada yang berhasil.
+func NewLDAPClient(cfg LDAPConfig) (*LDAPClient, error) {
+	servers, err := cfg.Servers()
+	if err != nil {
+		return nil, err
+	}
+
+	start := nextServerIndex(len(servers))
+	var errs []string
+	for i := range servers {
+		server := servers[(start+i)%len(servers)]
+		c, err := connectLDAP(cfg, server)
+		if err == nil {
+			return c, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return nil, fmt.Errorf("semua domain controller gagal: %s", strings.Join(errs, "; "))
+}
+
+// ListComputers mengambil semua objek komputer dari Active Directory.
+// Pencarian dihentikan jika ctx dibatalkan.
+func (c *LDAPClient) ListComputers(ctx context.Context) ([]*ldap.Entry, error) {
+	var entries []*ldap.Entry
+	err := c.ListComputersFunc(ctx, func(entry *ldap.Entry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return entries, nil
+}
 
-	return &LDAPClient{Conn: conn, Config: cfg}, nil
+// ListComputersFunc menjalankan paged search objek komputer dan memanggil fn untuk
+// setiap entry tanpa menampung seluruh hasil di memori. Jika fn mengembalikan error,
+// pencarian dihentikan dan error tersebut dikembalikan.
+func (c *LDAPClient) ListComputersFunc(ctx context.Context, fn func(*ldap.Entry) error) error {
+	filter := c.Config.Filter
+	if filter == "" {
+		filter = "(objectClass=computer)"
+	}
+	attributes := c.Config.Attributes
+	if len(attributes) == 0 {
+		attributes = DefaultLDAPAttributes
+	}
+	return c.SearchFunc(ctx, filter, attributes, fn)
 }
 
-// ListComputers mengambil semua objek komputer dari Active Directory
-func (c *LDAPClient) ListComputers() ([]*ldap.Entry, error) {
+// SearchFunc menjalankan paged search di SearchBase dengan filter dan atribut tertentu
+func (c *LDAPClient) SearchFunc(ctx context.Context, filter string, attributes []string, fn func(*ldap.Entry) error) error {
+	return c.searchBaseFunc(ctx, c.Config.SearchBase, filter, attributes, nil, fn)
+}
+
+// searchBaseFunc menjalankan paged search subtree di base tertentu dengan control tambahan
+func (c *LDAPClient) searchBaseFunc(ctx context.Context, base, filter string, attributes []string, controls []ldap.Control, fn func(*ldap.Entry) error) error {
+	pageSize := c.Config.PageSize
+	if pageSize <= 0 {
+		pageSize = 500
+	}
+	paging := ldap.NewControlPaging(uint32(pageSize))
 	searchRequest := ldap.NewSearchRequest(
-		c.Config.SearchBase,
+		base,
 		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
-		"(objectClass=computer)",
-		// TAMBAHKAN atribut operatingSystem dan operatingSystemVersion
-		[]string{"name", "operatingSystem", "operatingSystemVersion", "lastLogon", "lastLogonTimestamp", "whenChanged", "userAccountControl"},
-		nil,
+		filter,
+		attributes,
+		append([]ldap.Control{paging}, controls...),
 	)
 
-	sr, err := c.Conn.Search(searchRequest)
-	if err != nil {
-		return nil, fmt.Errorf("pencarian LDAP gagal: %v", err)
+	for {
+		cookie, err := c.searchPage(ctx, searchRequest, fn)
+		if err != nil {
+			return err
+		}
+		if len(cookie) == 0 {
+			return nil
+		}
+		paging.SetCookie(cookie)
 	}
+}
+
+// searchPage menjalankan satu halaman pencarian dan mengembalikan cookie halaman berikutnya
+func (c *LDAPClient) searchPage(ctx context.Context, req *ldap.SearchRequest, fn func(*ldap.Entry) error) ([]byte, error) {
+	pageCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	return sr.Entries, nil
+	var cookie []byte
+	res := c.Conn.SearchAsync(pageCtx, req, 0)
+	for res.Next() {
+		// Hasil berupa referral atau control tidak membawa entry
+		if entry := res.Entry(); entry != nil {
+			if err := fn(entry); err != nil {
+				// Hentikan pencarian dan kosongkan channel agar goroutine pencarian selesai
+				cancel()
+				for res.Next() {
+				}
+				return nil, err
+			}
+			continue
+		}
+		if ctrl, ok := ldap.FindControl(res.Controls(), ldap.ControlTypePaging).(*ldap.ControlPaging); ok {
+			cookie = ctrl.Cookie
+		}
+	}
+	if err := res.Err(); err != nil {
+		return nil, fmt.Errorf("pencarian LDAP gagal: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("pencarian LDAP dibatalkan: %v", err)
+	}
+	return cookie, nil
 }
 
 // Close menutup koneksi LDAP
@@ -81,6 +382,16 @@ func (c *LDAPClient) Close() {
 	}
 }
 
+// containsFold mengecek apakah list berisi s (tidak case-sensitive)
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
 func Atoi(s string) (int, error) {
 	var i int
 	_, err := fmt.Sscan(s, &i)
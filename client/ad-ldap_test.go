@@ -0,0 +1,410 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA adalah CA sementara untuk menerbitkan sertifikat server dan client
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue menerbitkan sertifikat leaf; dnsNames kosong berarti sertifikat client
+func (ca *testCA) issue(t *testing.T, cn string, dnsNames []string, ips []net.IP) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := x509.ExtKeyUsageServerAuth
+	if len(dnsNames) == 0 && len(ips) == 0 {
+		usage = x509.ExtKeyUsageClientAuth
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// handshakeResult adalah hasil handshake TLS yang dilihat stand-in
+type handshakeResult struct {
+	err        error
+	clientCert string // CN sertifikat client, kosong jika tidak ada
+}
+
+// startLDAPStandIn menjalankan pengganti DC lokal. Pada mode "ldaps" handshake TLS langsung dilakukan;
+// pada mode "starttls" stand-in menjawab Extended Request StartTLS lalu melakukan handshake.
+func startLDAPStandIn(t *testing.T, mode string, cfg *tls.Config) (port int, results <-chan handshakeResult) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	ch := make(chan handshakeResult, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(5 * time.Second))
+				if mode == "starttls" {
+					if err := answerStartTLS(conn); err != nil {
+						ch <- handshakeResult{err: err}
+						return
+					}
+				}
+				tc := tls.Server(conn, cfg)
+				if err := tc.Handshake(); err != nil {
+					ch <- handshakeResult{err: err}
+					return
+				}
+				res := handshakeResult{}
+				if peers := tc.ConnectionState().PeerCertificates; len(peers) > 0 {
+					res.clientCert = peers[0].Subject.CommonName
+				}
+				ch <- res
+				io.Copy(io.Discard, tc)
+			}(conn)
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, ch
+}
+
+// answerStartTLS membaca satu LDAPMessage (Extended Request StartTLS) dan membalas sukses
+func answerStartTLS(conn net.Conn) error {
+	msg, err := readBERElement(conn)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(string(msg), "1.3.6.1.4.1.1466.20037") {
+		return fmt.Errorf("request bukan StartTLS")
+	}
+	// Isi SEQUENCE: elemen pertama adalah messageID (INTEGER) yang dipakai ulang di response
+	content := msg[berHeaderLen(msg):]
+	idLen := 2 + int(content[1])
+	messageID := content[:idLen]
+	// [APPLICATION 24] ExtendedResponse { resultCode success, matchedDN "", diagnosticMessage "" }
+	extResp := []byte{0x78, 0x07, 0x0a, 0x01, 0x00, 0x04, 0x00, 0x04, 0x00}
+	body := append(append([]byte{}, messageID...), extResp...)
+	_, err = conn.Write(append([]byte{0x30, byte(len(body))}, body...))
+	return err
+}
+
+// readBERElement membaca satu elemen BER (tag, panjang, isi) dari koneksi
+func readBERElement(r io.Reader) ([]byte, error) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, err
+	}
+	length := int(head[1])
+	if head[1]&0x80 != 0 {
+		lenBytes := make([]byte, head[1]&0x7f)
+		if _, err := io.ReadFull(r, lenBytes); err != nil {
+			return nil, err
+		}
+		head = append(head, lenBytes...)
+		length = 0
+		for _, b := range lenBytes {
+			length = length<<8 | int(b)
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return append(head, body...), nil
+}
+
+// berHeaderLen mengembalikan panjang tag + length elemen BER
+func berHeaderLen(b []byte) int {
+	if b[1]&0x80 != 0 {
+		return 2 + int(b[1]&0x7f)
+	}
+	return 2
+}
+
+// tlsFixture berisi CA, sertifikat server untuk dc1.corp.test dan sertifikat client
+type tlsFixture struct {
+	dir        string
+	caFile     string
+	otherCA    string
+	clientCert string
+	clientKey  string
+	server     tls.Certificate
+	pool       *x509.CertPool
+}
+
+func newTLSFixture(t *testing.T) *tlsFixture {
+	t.Helper()
+	dir := t.TempDir()
+	ca := newTestCA(t, "Test Corp CA")
+	other := newTestCA(t, "Other CA")
+
+	srvCert, srvKey := ca.issue(t, "dc1.corp.test", []string{"dc1.corp.test"}, nil)
+	server, err := tls.X509KeyPair(srvCert, srvKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cliCert, cliKey := ca.issue(t, "inventory-svc", nil, nil)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return &tlsFixture{
+		dir:        dir,
+		caFile:     writeFile(t, dir, "ca.pem", ca.pem),
+		otherCA:    writeFile(t, dir, "other-ca.pem", other.pem),
+		clientCert: writeFile(t, dir, "client.pem", cliCert),
+		clientKey:  writeFile(t, dir, "client-key.pem", cliKey),
+		server:     server,
+		pool:       pool,
+	}
+}
+
+func (f *tlsFixture) serverConfig(requireClientCert bool) *tls.Config {
+	cfg := &tls.Config{Certificates: []tls.Certificate{f.server}, MinVersion: tls.VersionTLS12}
+	if requireClientCert {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		cfg.ClientCAs = f.pool
+	}
+	return cfg
+}
+
+// waitHandshake menunggu hasil handshake di sisi stand-in
+func waitHandshake(t *testing.T, results <-chan handshakeResult) handshakeResult {
+	t.Helper()
+	select {
+	case res := <-results:
+		return res
+	case <-time.After(5 * time.Second):
+		t.Fatal("stand-in tidak menerima koneksi")
+		return handshakeResult{}
+	}
+}
+
+func TestTLSConfigServerName(t *testing.T) {
+	tc, err := LDAPConfig{}.TLSConfig("dc1.corp.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.ServerName != "dc1.corp.test" || tc.MinVersion != tls.VersionTLS12 {
+		t.Errorf("ServerName = %q, MinVersion = %x", tc.ServerName, tc.MinVersion)
+	}
+	tc, err = LDAPConfig{ServerName: "ldap.corp.test"}.TLSConfig("10.0.0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.ServerName != "ldap.corp.test" {
+		t.Errorf("override ServerName = %q, want ldap.corp.test", tc.ServerName)
+	}
+}
+
+func TestTLSConfigInvalidFiles(t *testing.T) {
+	f := newTLSFixture(t)
+	notPEM := writeFile(t, f.dir, "bukan-pem.txt", []byte("bukan sertifikat"))
+
+	for name, cfg := range map[string]LDAPConfig{
+		"CA tidak ada":          {CAFile: filepath.Join(f.dir, "tidak-ada.pem")},
+		"CA bukan PEM":          {CAFile: notPEM},
+		"key client tidak ada":  {ClientCertFile: f.clientCert},
+		"cert client bukan PEM": {ClientCertFile: notPEM, ClientKeyFile: f.clientKey},
+	} {
+		if _, err := cfg.TLSConfig("dc1.corp.test"); err == nil {
+			t.Errorf("%s: TLSConfig harus gagal", name)
+		}
+	}
+}
+
+func TestDialLDAPTLS(t *testing.T) {
+	f := newTLSFixture(t)
+
+	tests := []struct {
+		name       string
+		standIn    string // mode stand-in: ldaps atau starttls
+		scheme     string
+		cfg        LDAPConfig
+		clientAuth bool
+		wantErr    bool   // dialLDAP harus gagal
+		wantClient string // CN sertifikat client yang diterima stand-in
+	}{
+		{
+			name: "ldaps dengan CA", standIn: "ldaps", scheme: "ldaps",
+			cfg: LDAPConfig{TLSMode: LDAPTLSLDAPS, CAFile: f.caFile, ServerName: "dc1.corp.test"},
+		},
+		{
+			name: "starttls dengan CA", standIn: "starttls", scheme: "ldap",
+			cfg: LDAPConfig{TLSMode: LDAPTLSStartTLS, CAFile: f.caFile, ServerName: "dc1.corp.test"},
+		},
+		{
+			// URL ldap:// pada mode ldaps di-upgrade dengan StartTLS, bukan dikirim tanpa enkripsi
+			name: "mode ldaps dengan URL ldap://", standIn: "starttls", scheme: "ldap",
+			cfg: LDAPConfig{TLSMode: LDAPTLSLDAPS, CAFile: f.caFile, ServerName: "dc1.corp.test"},
+		},
+		{
+			name: "CA lain ditolak", standIn: "ldaps", scheme: "ldaps",
+			cfg:     LDAPConfig{TLSMode: LDAPTLSLDAPS, CAFile: f.otherCA, ServerName: "dc1.corp.test"},
+			wantErr: true,
+		},
+		{
+			name: "CA lain ditolak pada starttls", standIn: "starttls", scheme: "ldap",
+			cfg:     LDAPConfig{TLSMode: LDAPTLSStartTLS, CAFile: f.otherCA, ServerName: "dc1.corp.test"},
+			wantErr: true,
+		},
+		{
+			// Tanpa LDAP_TLS_SERVER_NAME dipakai host 127.0.0.1 yang tidak ada di sertifikat
+			name: "tanpa override server name", standIn: "ldaps", scheme: "ldaps",
+			cfg:     LDAPConfig{TLSMode: LDAPTLSLDAPS, CAFile: f.caFile},
+			wantErr: true,
+		},
+		{
+			name: "sertifikat client", standIn: "ldaps", scheme: "ldaps", clientAuth: true,
+			cfg: LDAPConfig{TLSMode: LDAPTLSLDAPS, CAFile: f.caFile, ServerName: "dc1.corp.test",
+				ClientCertFile: f.clientCert, ClientKeyFile: f.clientKey},
+			wantClient: "inventory-svc",
+		},
+		{
+			name: "sertifikat client pada starttls", standIn: "starttls", scheme: "ldap", clientAuth: true,
+			cfg: LDAPConfig{TLSMode: LDAPTLSStartTLS, CAFile: f.caFile, ServerName: "dc1.corp.test",
+				ClientCertFile: f.clientCert, ClientKeyFile: f.clientKey},
+			wantClient: "inventory-svc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, results := startLDAPStandIn(t, tt.standIn, f.serverConfig(tt.clientAuth))
+			conn, err := dialLDAP(tt.cfg, LDAPServer{Scheme: tt.scheme, Host: "127.0.0.1", Port: port})
+			if conn != nil {
+				defer conn.Close()
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("dialLDAP harus gagal")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("dialLDAP: %v", err)
+			}
+			res := waitHandshake(t, results)
+			if res.err != nil {
+				t.Fatalf("handshake di stand-in gagal: %v", res.err)
+			}
+			if res.clientCert != tt.wantClient {
+				t.Errorf("sertifikat client = %q, want %q", res.clientCert, tt.wantClient)
+			}
+		})
+	}
+}
+
+// Stand-in yang mewajibkan sertifikat client harus menolak koneksi tanpa sertifikat.
+// Pada TLS 1.3 penolakan baru terlihat di sisi server, jadi hasilnya diperiksa di stand-in.
+func TestDialLDAPTLSClientCertRequired(t *testing.T) {
+	f := newTLSFixture(t)
+	port, results := startLDAPStandIn(t, "ldaps", f.serverConfig(true))
+	cfg := LDAPConfig{TLSMode: LDAPTLSLDAPS, CAFile: f.caFile, ServerName: "dc1.corp.test"}
+	conn, err := dialLDAP(cfg, LDAPServer{Scheme: "ldaps", Host: "127.0.0.1", Port: port})
+	if conn != nil {
+		defer conn.Close()
+	}
+	if err == nil {
+		if res := waitHandshake(t, results); res.err == nil {
+			t.Fatal("stand-in harus menolak koneksi tanpa sertifikat client")
+		}
+	}
+}
+
+func TestDialLDAPPlainOnlyWhenTLSDisabled(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() { io.Copy(io.Discard, conn); conn.Close() }()
+		}
+	}()
+	server := LDAPServer{Scheme: "ldap", Host: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port}
+
+	conn, err := dialLDAP(LDAPConfig{TLSMode: LDAPTLSNone}, server)
+	if err != nil {
+		t.Fatalf("mode none: %v", err)
+	}
+	conn.Close()
+
+	if _, err := dialLDAP(LDAPConfig{TLSMode: "tls"}, server); err == nil {
+		t.Error("mode TLS tidak dikenal harus ditolak")
+	}
+}
@@ -0,0 +1,208 @@
+// client/elasticsearch-alias.go
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// responseError mengubah response error Elasticsearch menjadi error Go
+func responseError(action string, res *esapi.Response) error {
+	msg, _ := io.ReadAll(res.Body)
+	return fmt.Errorf("%s: %s: %s", action, res.Status(), msg)
+}
+
+// CreateIndex membuat index baru; mapping diambil dari index template yang cocok
+func (c *ElasticsearchClient) CreateIndex(ctx context.Context, index string) error {
+	es := c.Client
+	res, err := es.Indices.Create(index, es.Indices.Create.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gagal membuat index %s: %v", index, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return responseError("gagal membuat index "+index, res)
+	}
+	return nil
+}
+
+// DeleteIndex menghapus index; index yang sudah tidak ada tidak dianggap error
+func (c *ElasticsearchClient) DeleteIndex(ctx context.Context, index string) error {
+	es := c.Client
+	res, err := es.Indices.Delete([]string{index}, es.Indices.Delete.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gagal menghapus index %s: %v", index, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() && res.StatusCode != http.StatusNotFound {
+		return responseError("gagal menghapus index "+index, res)
+	}
+	return nil
+}
+
+// ListIndices mengembalikan nama index (bukan alias) yang cocok dengan pattern, terurut menaik
+func (c *ElasticsearchClient) ListIndices(ctx context.Context, pattern string) ([]string, error) {
+	es := c.Client
+	res, err := es.Indices.Get([]string{pattern},
+		es.Indices.Get.WithContext(ctx),
+		es.Indices.Get.WithIgnoreUnavailable(true),
+		es.Indices.Get.WithAllowNoIndices(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca daftar index %s: %v", pattern, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, responseError("gagal membaca daftar index "+pattern, res)
+	}
+	var resp map[string]json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("gagal decode daftar index %s: %v", pattern, err)
+	}
+	indices := make([]string, 0, len(resp))
+	for name := range resp {
+		indices = append(indices, name)
+	}
+	sort.Strings(indices)
+	return indices, nil
+}
+
+// IndexExists memeriksa apakah nama tersebut adalah index konkret (bukan alias)
+func (c *ElasticsearchClient) IndexExists(ctx context.Context, name string) (bool, error) {
+	indices, err := c.ListIndices(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	for _, idx := range indices {
+		if idx == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Alias adalah alias index, opsional dengan filter (mis. view dokumen tertentu)
+type Alias struct {
+	Name   string
+	Filter map[string]interface{}
+}
+
+func (a Alias) addAction(index string) map[string]interface{} {
+	add := map[string]interface{}{"index": index, "alias": a.Name}
+	if a.Filter != nil {
+		add["filter"] = a.Filter
+	}
+	return map[string]interface{}{"add": add}
+}
+
+// SwapAlias memindahkan alias ke newIndex secara atomik.
+// Alias dilepas dari semua index lain yang cocok dengan pattern, dan index konkret di removeIndices
+// (mis. index lama yang bernama sama dengan alias) dihapus dalam aksi yang sama.
+func (c *ElasticsearchClient) SwapAlias(ctx context.Context, aliases []Alias, pattern, newIndex string, removeIndices []string) error {
+	var actions []map[string]interface{}
+	for _, a := range aliases {
+		actions = append(actions, map[string]interface{}{
+			"remove": map[string]interface{}{"index": pattern, "alias": a.Name, "must_exist": false},
+		})
+	}
+	for _, idx := range removeIndices {
+		actions = append(actions, map[string]interface{}{"remove_index": map[string]interface{}{"index": idx}})
+	}
+	for _, a := range aliases {
+		actions = append(actions, a.addAction(newIndex))
+	}
+	return c.updateAliases(ctx, actions, "gagal memindahkan alias ke "+newIndex)
+}
+
+// PutAliases memasang (atau memperbarui filter) alias pada index yang sudah ada
+func (c *ElasticsearchClient) PutAliases(ctx context.Context, index string, aliases []Alias) error {
+	var actions []map[string]interface{}
+	for _, a := range aliases {
+		actions = append(actions, a.addAction(index))
+	}
+	return c.updateAliases(ctx, actions, "gagal memasang alias pada "+index)
+}
+
+// updateAliases mengirim aksi ke _aliases API
+func (c *ElasticsearchClient) updateAliases(ctx context.Context, actions []map[string]interface{}, action string) error {
+	body, err := json.Marshal(map[string]interface{}{"actions": actions})
+	if err != nil {
+		return err
+	}
+	es := c.Client
+	res, err := es.Indices.UpdateAliases(bytes.NewReader(body), es.Indices.UpdateAliases.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("%s: %v", action, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return responseError(action, res)
+	}
+	return nil
+}
+
+// AliasIndices mengembalikan index yang saat ini ditunjuk oleh alias
+func (c *ElasticsearchClient) AliasIndices(ctx context.Context, alias string) ([]string, error) {
+	es := c.Client
+	res, err := es.Indices.GetAlias(es.Indices.GetAlias.WithName(alias), es.Indices.GetAlias.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca alias %s: %v", alias, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, responseError("gagal membaca alias "+alias, res)
+	}
+	var resp map[string]json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("gagal decode alias %s: %v", alias, err)
+	}
+	var indices []string
+	for name := range resp {
+		indices = append(indices, name)
+	}
+	sort.Strings(indices)
+	return indices, nil
+}
+
+// isSnapshotIndexOf memeriksa apakah index adalah generasi versi dari alias, mis. "inventory-20261017T120000"
+func isSnapshotIndexOf(alias, index string) bool {
+	suffix := strings.TrimPrefix(index, alias+"-")
+	if suffix == index || len(suffix) != len(SnapshotIndexLayout) {
+		return false
+	}
+	return strings.IndexFunc(suffix, func(r rune) bool {
+		return (r < '0' || r > '9') && r != 'T'
+	}) < 0
+}
+
+// SnapshotIndexLayout adalah format waktu (UTC) pada nama index berversi
+const SnapshotIndexLayout = "20060102T150405"
+
+// SnapshotIndices mengembalikan index berversi milik alias, terurut dari yang tertua
+func (c *ElasticsearchClient) SnapshotIndices(ctx context.Context, alias string) ([]string, error) {
+	all, err := c.ListIndices(ctx, alias+"-*")
+	if err != nil {
+		return nil, err
+	}
+	var indices []string
+	for _, idx := range all {
+		if isSnapshotIndexOf(alias, idx) {
+			indices = append(indices, idx)
+		}
+	}
+	return indices, nil
+}
@@ -3,7 +3,10 @@ package client
 
 import (
 	"fmt"
+	"log"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
 )
@@ -14,15 +17,44 @@ type ElasticsearchConfig struct {
 	Username string
 	Password string
 	Index    string
+
+	// Pengaturan Bulk API
+	BulkFlushBytes int
+	BulkWorkers    int
+	BulkMaxRetries int
+
+	// Mode index berversi: Index dipakai sebagai alias ke snapshot terbaru
+	VersionedIndices  bool
+	RetainGenerations int // generasi lama yang disimpan selain index aktif, minimal 0
+
+	// Data stream riwayat harian (kosong = nonaktif), masa simpan dan umur rollover index-nya
+	HistoryStream    string
+	HistoryRetention string
+	HistoryRollover  string
 }
 
 func LoadElasticsearchConfig() ElasticsearchConfig {
-	return ElasticsearchConfig{
-		URL:      os.Getenv("ELASTICSEARCH_SERVER_URL"),
-		Username: os.Getenv("ELASTICSEARCH_USER"),
-		Password: os.Getenv("ELASTICSEARCH_PASS"),
-		Index:    os.Getenv("ELASTICSEARCH_INDEX_NAME"),
+	cfg := ElasticsearchConfig{
+		URL:            os.Getenv("ELASTICSEARCH_SERVER_URL"),
+		Username:       os.Getenv("ELASTICSEARCH_USER"),
+		Password:       os.Getenv("ELASTICSEARCH_PASS"),
+		Index:          os.Getenv("ELASTICSEARCH_INDEX_NAME"),
+		BulkFlushBytes: envInt("ELASTICSEARCH_BULK_FLUSH_BYTES", 5*1024*1024),
+		BulkWorkers:    envInt("ELASTICSEARCH_BULK_WORKERS", 4),
+		BulkMaxRetries: envInt("ELASTICSEARCH_BULK_MAX_RETRIES", 3),
+
+		VersionedIndices:  os.Getenv("ELASTICSEARCH_VERSIONED_INDICES") == "true",
+		RetainGenerations: envInt("ELASTICSEARCH_RETAIN_GENERATIONS", 3),
+
+		HistoryStream:    os.Getenv("ELASTICSEARCH_HISTORY_STREAM"),
+		HistoryRetention: envString("ELASTICSEARCH_HISTORY_RETENTION", "365d"),
+		HistoryRollover:  envString("ELASTICSEARCH_HISTORY_ROLLOVER", "30d"),
 	}
+	if cfg.RetainGenerations < 0 {
+		log.Printf("[WARN] Elasticsearch - ELASTICSEARCH_RETAIN_GENERATIONS=%d tidak valid, dipakai 0 (hanya index aktif yang disimpan)", cfg.RetainGenerations)
+		cfg.RetainGenerations = 0
+	}
+	return cfg
 }
 
 // ElasticsearchClient adalah client untuk koneksi Elasticsearch
@@ -36,6 +68,15 @@ func NewElasticsearchClient(cfg ElasticsearchConfig) (*ElasticsearchClient, erro
 		Addresses: []string{cfg.URL},
 		Username:  cfg.Username,
 		Password:  cfg.Password,
+		// Retry di level request untuk Elasticsearch yang sedang sibuk/overload. Lapisan ini mengulang
+		// seluruh request HTTP; item Bulk yang ditolak 429/503 di dalam response sukses diulang terpisah
+		// oleh Bulk. Keduanya memakai BulkMaxRetries, sehingga satu item paling banyak dikirim
+		// (BulkMaxRetries+1)^2 kali. Nilai 0 mematikan kedua lapisan (elastictransport menganggap
+		// MaxRetries 0 sebagai default 3, jadi dipakai DisableRetry).
+		RetryOnStatus: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		MaxRetries:    cfg.BulkMaxRetries,
+		DisableRetry:  cfg.BulkMaxRetries <= 0,
+		RetryBackoff:  bulkBackoff,
 	}
 	es, err := elasticsearch.NewClient(esCfg)
 	if err != nil {
@@ -43,3 +84,33 @@ func NewElasticsearchClient(cfg ElasticsearchConfig) (*ElasticsearchClient, erro
 	}
 	return &ElasticsearchClient{Client: es, Config: cfg}, nil
 }
+
+// bulkBackoff menghitung jeda exponential (200ms, 400ms, 800ms, ...) dengan batas 10 detik
+func bulkBackoff(attempt int) time.Duration {
+	d := 100 * time.Millisecond << uint(attempt)
+	if d <= 0 || d > 10*time.Second {
+		d = 10 * time.Second
+	}
+	return d
+}
+
+// envString membaca environment variable, atau mengembalikan nilai default jika kosong
+func envString(key, def string) string {
+	if s := os.Getenv(key); s != "" {
+		return s
+	}
+	return def
+}
+
+// envInt membaca environment variable integer, atau mengembalikan nilai default
+func envInt(key string, def int) int {
+	s := os.Getenv(key)
+	if s == "" {
+		return def
+	}
+	i, err := Atoi(s)
+	if err != nil {
+		return def
+	}
+	return i
+}
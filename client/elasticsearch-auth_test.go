@@ -0,0 +1,20 @@
+package client
+
+import "testing"
+
+func TestLoadElasticsearchConfigRetainGenerations(t *testing.T) {
+	for _, tc := range []struct {
+		env  string
+		want int
+	}{
+		{"", 3},
+		{"5", 5},
+		{"0", 0},
+		{"-2", 0},
+	} {
+		t.Setenv("ELASTICSEARCH_RETAIN_GENERATIONS", tc.env)
+		if got := LoadElasticsearchConfig().RetainGenerations; got != tc.want {
+			t.Errorf("ELASTICSEARCH_RETAIN_GENERATIONS=%q: RetainGenerations = %d, want %d", tc.env, got, tc.want)
+		}
+	}
+}
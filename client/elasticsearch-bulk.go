@@ -0,0 +1,179 @@
+// client/elasticsearch-bulk.go
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+)
+
+// BulkOperation adalah satu operasi (index, create atau delete) yang dikirim lewat Bulk API
+type BulkOperation struct {
+	Action     string // "index", "create" atau "delete"
+	DocumentID string
+	Body       []byte // Dokumen untuk action "index" dan "create"; diabaikan untuk "delete"
+}
+
+// BulkItemError menyimpan detail kegagalan per dokumen dari response Bulk API
+type BulkItemError struct {
+	Action     string
+	DocumentID string
+	Status     int
+	Reason     string
+}
+
+func (e BulkItemError) Error() string {
+	return fmt.Sprintf("%s %s gagal (status %d): %s", e.Action, e.DocumentID, e.Status, e.Reason)
+}
+
+// BulkResult merangkum hasil eksekusi Bulk API
+type BulkResult struct {
+	Indexed  int
+	Deleted  int
+	Failed   int
+	Requests uint64
+	Errors   []BulkItemError
+}
+
+// isRetryableStatus menentukan status item yang layak dikirim ulang
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// Bulk mengirim operasi index/delete ke index yang diberikan menggunakan esutil.BulkIndexer.
+// Item yang gagal dengan status 429/503 akan dikirim ulang hingga BulkMaxRetries kali.
+// Item yang tidak pernah mendapat respons (request _bulk gagal seluruhnya) dihitung di Failed.
+func (c *ElasticsearchClient) Bulk(ctx context.Context, index string, ops []BulkOperation) (BulkResult, error) {
+	var result BulkResult
+	pending := ops
+
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return result, ctx.Err()
+			case <-time.After(bulkBackoff(attempt)):
+			}
+		}
+		lastAttempt := attempt >= c.Config.BulkMaxRetries
+
+		retry, err := c.bulkOnce(ctx, index, pending, lastAttempt, &result)
+		if err != nil {
+			return result, err
+		}
+		pending = retry
+	}
+	return result, nil
+}
+
+// bulkOnce menjalankan satu putaran BulkIndexer dan mengembalikan item yang perlu di-retry
+func (c *ElasticsearchClient) bulkOnce(ctx context.Context, index string, ops []BulkOperation, lastAttempt bool, result *BulkResult) ([]BulkOperation, error) {
+	var (
+		mu       sync.Mutex
+		retry    []BulkOperation
+		acked    = make([]bool, len(ops)) // Item yang sudah mendapat OnSuccess/OnFailure
+		flushErr error                    // Error request _bulk secara keseluruhan (5xx, jaringan)
+	)
+
+	bi, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
+		Client:     c.Client,
+		Index:      index,
+		NumWorkers: c.Config.BulkWorkers,
+		FlushBytes: c.Config.BulkFlushBytes,
+		// Jika seluruh request gagal, esutil tidak memanggil OnFailure per item
+		OnError: func(_ context.Context, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if flushErr == nil {
+				flushErr = err
+			}
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuat bulk indexer: %v", err)
+	}
+
+	for i, op := range ops {
+		i, op := i, op
+		item := esutil.BulkIndexerItem{
+			Action:     op.Action,
+			DocumentID: op.DocumentID,
+			OnSuccess: func(_ context.Context, _ esutil.BulkIndexerItem, _ esutil.BulkIndexerResponseItem) {
+				mu.Lock()
+				defer mu.Unlock()
+				acked[i] = true
+				if op.Action == "delete" {
+					result.Deleted++
+				} else {
+					result.Indexed++
+				}
+			},
+			OnFailure: func(_ context.Context, _ esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				acked[i] = true
+				// Dokumen yang sudah tidak ada tidak dihitung sebagai kegagalan hapus
+				if op.Action == "delete" && res.Status == http.StatusNotFound {
+					result.Deleted++
+					return
+				}
+				// Dokumen "create" dengan ID yang sama sudah ditulis pada percobaan sebelumnya
+				if op.Action == "create" && res.Status == http.StatusConflict {
+					result.Indexed++
+					return
+				}
+				if !lastAttempt && isRetryableStatus(res.Status) {
+					retry = append(retry, op)
+					return
+				}
+				reason := res.Error.Reason
+				if err != nil {
+					reason = err.Error()
+				}
+				result.Failed++
+				result.Errors = append(result.Errors, BulkItemError{
+					Action:     op.Action,
+					DocumentID: op.DocumentID,
+					Status:     res.Status,
+					Reason:     reason,
+				})
+			},
+		}
+		if op.Body != nil {
+			item.Body = bytes.NewReader(op.Body)
+		}
+		if err := bi.Add(ctx, item); err != nil {
+			bi.Close(ctx)
+			return nil, fmt.Errorf("gagal menambahkan item %s ke bulk indexer: %v", op.DocumentID, err)
+		}
+	}
+
+	if err := bi.Close(ctx); err != nil {
+		return nil, fmt.Errorf("gagal menutup bulk indexer: %v", err)
+	}
+	result.Requests += bi.Stats().NumRequests
+
+	// Item tanpa respons berada di request _bulk yang gagal seluruhnya; transport sudah
+	// mengulang request tersebut, sehingga item dihitung gagal agar tidak dianggap tersimpan.
+	reason := "tidak ada respons dari Bulk API"
+	if flushErr != nil {
+		reason = flushErr.Error()
+	}
+	for i, op := range ops {
+		if acked[i] {
+			continue
+		}
+		result.Failed++
+		result.Errors = append(result.Errors, BulkItemError{
+			Action:     op.Action,
+			DocumentID: op.DocumentID,
+			Reason:     reason,
+		})
+	}
+	return retry, nil
+}
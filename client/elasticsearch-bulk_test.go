@@ -0,0 +1,211 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeBulkServer adalah pengganti lokal endpoint _bulk Elasticsearch.
+// statuses menentukan status per dokumen untuk setiap percobaan; percobaan di luar daftar memakai status terakhir.
+type fakeBulkServer struct {
+	mu          sync.Mutex
+	statuses    map[string][]int
+	attempts    map[string]int
+	requests    int
+	failRequest int // status untuk seluruh request HTTP (0 = normal)
+}
+
+func newFakeBulkServer(t *testing.T, statuses map[string][]int) (*fakeBulkServer, *httptest.Server) {
+	t.Helper()
+	f := &fakeBulkServer{statuses: statuses, attempts: make(map[string]int)}
+	srv := httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeBulkServer) handle(w http.ResponseWriter, r *http.Request) {
+	// Client v8 memeriksa header ini untuk memastikan server adalah Elasticsearch
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+	if f.failRequest != 0 {
+		w.WriteHeader(f.failRequest)
+		w.Write([]byte(`{"error":"overloaded"}`))
+		return
+	}
+	if !strings.HasSuffix(r.URL.Path, "/_bulk") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var items []map[string]interface{}
+	sc := bufio.NewScanner(r.Body)
+	sc.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for sc.Scan() {
+		var meta map[string]map[string]interface{}
+		if err := json.Unmarshal(sc.Bytes(), &meta); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for action, m := range meta {
+			if action != "delete" {
+				sc.Scan() // lewati baris body
+			}
+			id, _ := m["_id"].(string)
+			seq := f.statuses[id]
+			status := http.StatusCreated
+			if action == "delete" {
+				status = http.StatusOK
+			}
+			if n := f.attempts[id]; len(seq) > 0 {
+				if n >= len(seq) {
+					n = len(seq) - 1
+				}
+				status = seq[n]
+			}
+			f.attempts[id]++
+
+			res := map[string]interface{}{"_index": "inventory", "_id": id, "status": status}
+			if status >= 300 {
+				res["error"] = map[string]interface{}{"type": "test_exception", "reason": "status " + http.StatusText(status)}
+			}
+			items = append(items, map[string]interface{}{action: res})
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"took": 1, "errors": true, "items": items})
+}
+
+func newTestESClient(t *testing.T, url string, maxRetries int) *ElasticsearchClient {
+	t.Helper()
+	c, err := NewElasticsearchClient(ElasticsearchConfig{
+		URL:            url,
+		BulkFlushBytes: 1024 * 1024,
+		BulkWorkers:    1,
+		BulkMaxRetries: maxRetries,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestBulkItemResults(t *testing.T) {
+	f, srv := newFakeBulkServer(t, map[string][]int{
+		"bad":     {http.StatusBadRequest},
+		"busy":    {http.StatusTooManyRequests, http.StatusCreated},
+		"gone":    {http.StatusNotFound},
+		"dup":     {http.StatusConflict},
+		"missing": {http.StatusNotFound},
+	})
+	c := newTestESClient(t, srv.URL, 3)
+
+	body := []byte(`{"computer_name":"PC"}`)
+	res, err := c.Bulk(context.Background(), "inventory", []BulkOperation{
+		{Action: "index", DocumentID: "ok", Body: body},
+		{Action: "index", DocumentID: "bad", Body: body},
+		{Action: "index", DocumentID: "busy", Body: body},
+		{Action: "delete", DocumentID: "gone"},
+		{Action: "delete", DocumentID: "del"},
+		{Action: "create", DocumentID: "dup", Body: body},
+		{Action: "index", DocumentID: "missing", Body: body},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ok, busy (setelah retry) dan dup (409 pada create) terindeks
+	if res.Indexed != 3 {
+		t.Errorf("Indexed = %d, want 3", res.Indexed)
+	}
+	// 404 pada delete dihitung terhapus
+	if res.Deleted != 2 {
+		t.Errorf("Deleted = %d, want 2", res.Deleted)
+	}
+	// 400 dan 404 pada index adalah kegagalan per item, tidak di-retry
+	if res.Failed != 2 || len(res.Errors) != 2 {
+		t.Fatalf("Failed = %d, Errors = %v, want 2", res.Failed, res.Errors)
+	}
+	failed := map[string]BulkItemError{}
+	for _, e := range res.Errors {
+		failed[e.DocumentID] = e
+	}
+	if e := failed["bad"]; e.Status != http.StatusBadRequest || e.Action != "index" || !strings.Contains(e.Reason, "Bad Request") {
+		t.Errorf("error bad = %+v", e)
+	}
+	if _, ok := failed["missing"]; !ok {
+		t.Errorf("404 pada index harus gagal, errors = %v", res.Errors)
+	}
+	if f.attempts["busy"] != 2 || f.attempts["bad"] != 1 {
+		t.Errorf("attempts busy=%d bad=%d, want 2 dan 1", f.attempts["busy"], f.attempts["bad"])
+	}
+	if res.Requests != 2 {
+		t.Errorf("Requests = %d, want 2 (awal + retry item 429)", res.Requests)
+	}
+}
+
+func TestBulkMaxRetriesCutoff(t *testing.T) {
+	for _, tc := range []struct {
+		maxRetries   int
+		wantAttempts int
+	}{
+		{maxRetries: 0, wantAttempts: 1},
+		{maxRetries: 2, wantAttempts: 3},
+	} {
+		f, srv := newFakeBulkServer(t, map[string][]int{"busy": {http.StatusServiceUnavailable}})
+		c := newTestESClient(t, srv.URL, tc.maxRetries)
+
+		res, err := c.Bulk(context.Background(), "inventory", []BulkOperation{
+			{Action: "index", DocumentID: "busy", Body: []byte(`{}`)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.attempts["busy"] != tc.wantAttempts {
+			t.Errorf("maxRetries=%d: attempts = %d, want %d", tc.maxRetries, f.attempts["busy"], tc.wantAttempts)
+		}
+		if res.Failed != 1 || len(res.Errors) != 1 || res.Errors[0].Status != http.StatusServiceUnavailable {
+			t.Errorf("maxRetries=%d: Failed = %d, Errors = %v", tc.maxRetries, res.Failed, res.Errors)
+		}
+	}
+}
+
+func TestBulkTransportRetryDisabledAtZero(t *testing.T) {
+	for _, tc := range []struct {
+		maxRetries   int
+		wantRequests int
+	}{
+		{maxRetries: 0, wantRequests: 1},
+		{maxRetries: 1, wantRequests: 2},
+	} {
+		f, srv := newFakeBulkServer(t, nil)
+		f.failRequest = http.StatusServiceUnavailable
+		c := newTestESClient(t, srv.URL, tc.maxRetries)
+
+		// Seluruh request 503: transport mengulang hingga MaxRetries, lalu semua item dihitung gagal
+		res, err := c.Bulk(context.Background(), "inventory", []BulkOperation{
+			{Action: "index", DocumentID: "a", Body: []byte(`{}`)},
+			{Action: "delete", DocumentID: "b"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.requests != tc.wantRequests {
+			t.Errorf("maxRetries=%d: request HTTP = %d, want %d", tc.maxRetries, f.requests, tc.wantRequests)
+		}
+		if res.Indexed != 0 || res.Deleted != 0 || res.Failed != 2 || len(res.Errors) != 2 {
+			t.Fatalf("maxRetries=%d: Indexed=%d Deleted=%d Failed=%d Errors=%v, want 0/0/2", tc.maxRetries, res.Indexed, res.Deleted, res.Failed, res.Errors)
+		}
+		if !strings.Contains(res.Errors[0].Reason, "503") {
+			t.Errorf("maxRetries=%d: reason = %q, want status 503", tc.maxRetries, res.Errors[0].Reason)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// client/elasticsearch-template.go
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// mappingProperty membangun definisi satu field mapping dari nama tipenya
+func mappingProperty(typ string) map[string]interface{} {
+	prop := map[string]interface{}{"type": typ}
+	if typ == "text" {
+		// Field teks tetap bisa dipakai untuk agregasi/sort lewat sub-field keyword
+		prop["fields"] = map[string]interface{}{
+			"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 1024},
+		}
+	}
+	return prop
+}
+
+// IndexTemplate adalah definisi composable index template yang dipasang aplikasi
+type IndexTemplate struct {
+	Patterns   []string
+	Properties map[string]string // nama field -> tipe Elasticsearch (keyword, date, boolean, ...)
+	DataStream bool              // Template untuk data stream (append-only, wajib punya @timestamp)
+	Settings   map[string]interface{}
+	Priority   int // Default 200; naikkan jika pattern tumpang tindih dengan template lain
+}
+
+// PutIndexTemplate memasang (atau memperbarui) composable index template dengan mapping eksplisit
+func (c *ElasticsearchClient) PutIndexTemplate(ctx context.Context, name string, tmpl IndexTemplate) error {
+	props := make(map[string]interface{}, len(tmpl.Properties))
+	for field, typ := range tmpl.Properties {
+		props[field] = mappingProperty(typ)
+	}
+	template := map[string]interface{}{
+		"mappings": map[string]interface{}{
+			"properties": props,
+		},
+	}
+	if len(tmpl.Settings) > 0 {
+		template["settings"] = tmpl.Settings
+	}
+	priority := tmpl.Priority
+	if priority == 0 {
+		priority = 200
+	}
+	def := map[string]interface{}{
+		"index_patterns": tmpl.Patterns,
+		"priority":       priority,
+		"template":       template,
+	}
+	if tmpl.DataStream {
+		def["data_stream"] = map[string]interface{}{}
+	}
+	body, err := json.Marshal(def)
+	if err != nil {
+		return err
+	}
+
+	es := c.Client
+	res, err := es.Indices.PutIndexTemplate(name, bytes.NewReader(body), es.Indices.PutIndexTemplate.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gagal memasang index template %s: %v", name, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return responseError("gagal memasang index template "+name, res)
+	}
+	return nil
+}
+
+// IndexMappingConflicts membandingkan mapping index yang sudah ada dengan properties yang diharapkan.
+// Mengembalikan daftar field yang tipenya berbeda; index yang belum ada dianggap tidak konflik.
+func (c *ElasticsearchClient) IndexMappingConflicts(ctx context.Context, index string, properties map[string]string) ([]string, error) {
+	es := c.Client
+	res, err := es.Indices.GetMapping(es.Indices.GetMapping.WithIndex(index), es.Indices.GetMapping.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca mapping index %s: %v", index, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, responseError("gagal membaca mapping index "+index, res)
+	}
+
+	// Response berisi satu entry per index fisik (index bisa berupa alias)
+	var resp map[string]struct {
+		Mappings struct {
+			Properties map[string]struct {
+				Type string `json:"type"`
+			} `json:"properties"`
+		} `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("gagal decode mapping index %s: %v", index, err)
+	}
+
+	var conflicts []string
+	for physical, m := range resp {
+		for field, want := range properties {
+			got, ok := m.Mappings.Properties[field]
+			if !ok || got.Type == want {
+				continue
+			}
+			gotType := got.Type
+			if gotType == "" {
+				gotType = "object"
+			}
+			conflicts = append(conflicts, fmt.Sprintf("%s.%s: %s (seharusnya %s)", physical, field, gotType, want))
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts, nil
+}
+
+// PutRetentionPolicy memasang ILM policy yang melakukan rollover index aktif setiap rolloverAge
+// dan menghapus index setelah retention (mis. "365d")
+func (c *ElasticsearchClient) PutRetentionPolicy(ctx context.Context, name, rolloverAge, retention string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"policy": map[string]interface{}{
+			"phases": map[string]interface{}{
+				"hot": map[string]interface{}{
+					"actions": map[string]interface{}{
+						"rollover": map[string]interface{}{
+							"max_age":                rolloverAge,
+							"max_primary_shard_size": "50gb",
+						},
+					},
+				},
+				"delete": map[string]interface{}{
+					"min_age": retention,
+					"actions": map[string]interface{}{"delete": map[string]interface{}{}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	es := c.Client
+	res, err := es.ILM.PutLifecycle(name, es.ILM.PutLifecycle.WithBody(bytes.NewReader(body)), es.ILM.PutLifecycle.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gagal memasang ILM policy %s: %v", name, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return responseError("gagal memasang ILM policy "+name, res)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// client/ocs-hardware-delete.go
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"ocs-ad-inventorymanagement/parser"
+
+	"gorm.io/gorm"
+)
+
+// Regex untuk validasi nama tabel sebagai lapisan pertahanan tambahan (defense-in-depth)
+var validTableName = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// Whitelist nama tabel OCS yang boleh dihapus barisnya berdasarkan HARDWARE_ID
+var allowedHardwareTables = map[string]struct{}{
+	"accesslog": {}, "accountinfo": {}, "accountinfo_config": {}, "archive": {}, "assets_categories": {}, "auth_attempt": {}, "batteries": {}, "bios": {}, "blacklist_macaddresses": {}, "blacklist_serials": {}, "blacklist_subnet": {}, "config": {}, "config_ldap": {}, "conntrack": {}, "controllers": {}, "cpus": {}, "cve_search": {}, "cve_search_computer": {}, "cve_search_correspondance": {}, "cve_search_history": {}, "deleted_equiv": {}, "deploy": {}, "devices": {}, "devicetype": {}, "dico_ignored": {}, "dico_soft": {}, "download_affect_rules": {}, "download_available": {}, "download_enable": {}, "download_history": {}, "download_servers": {}, "downloadwk_conf_values": {}, "downloadwk_fields": {}, "downloadwk_history": {}, "downloadwk_pack": {}, "downloadwk_statut_request": {}, "downloadwk_tab_values": {}, "drives": {}, "engine_mutex": {}, "engine_persistent": {}, "extensions": {}, "files": {}, "groups": {}, "groups_cache": {}, "hardware": {}, "hardware_osname_cache": {}, "history": {}, "inputs": {}, "itmgmt_comments": {}, "javainfo": {}, "journallog": {}, "languages": {}, "layouts": {}, "local_groups": {}, "local_users": {}, "locks": {}, "memories": {}, "modems": {}, "monitors": {}, "netmap": {}, "network_devices": {}, "networks": {}, "notification": {}, "notification_config": {}, "ports": {}, "printers": {}, "prolog_conntrack": {}, "regconfig": {}, "registry": {}, "registry_name_cache": {}, "registry_regvalue_cache": {}, "reports_notifications": {}, "repository": {}, "saas": {}, "saas_exp": {}, "save_query": {}, "schedule_wol": {}, "sim": {}, "slots": {}, "snmp_accountinfo": {}, "snmp_communities": {}, "snmp_configs": {}, "snmp_default": {}, "snmp_labels": {}, "snmp_mibs": {}, "snmp_ocs": {}, "snmp_types": {}, "snmp_types_conditions": {}, "software": {}, "software_categories": {}, "software_categories_link": {}, "software_category_exp": {}, "software_link": {}, "software_name": {}, "software_publisher": {}, "software_version": {}, "softwares_name_cache": {}, "sounds": {}, "ssl_store": {}, "storages": {}, "subnet": {}, "tags": {}, "temp_files": {}, "usbdevices": {}, "videos": {}, "virtualmachines": {},
+}
+
+// HardwareTables mengembalikan tabel di skema OCS saat ini yang punya kolom HARDWARE_ID.
+// Tabel yang namanya tidak valid atau tidak ada di whitelist dilewati.
+func HardwareTables(db *gorm.DB) ([]string, error) {
+	type tableRow struct {
+		TableName string `gorm:"column:TABLE_NAME"`
+	}
+	var rows []tableRow
+	query := `
+		SELECT DISTINCT TABLE_NAME
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND COLUMN_NAME = 'HARDWARE_ID'
+	`
+	if err := db.Raw(query).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil daftar tabel: %v", err)
+	}
+	var tables []string
+	for _, t := range rows {
+		if !validTableName.MatchString(t.TableName) {
+			continue
+		}
+		if _, ok := allowedHardwareTables[t.TableName]; !ok {
+			continue
+		}
+		tables = append(tables, t.TableName)
+	}
+	sort.Strings(tables)
+	return tables, nil
+}
+
+// DeleteHardwareRows menghapus semua baris yang terkait dengan ID hardware lalu baris hardware-nya.
+// tx sebaiknya sebuah transaksi; jumlah baris terhapus dikembalikan per tabel.
+func DeleteHardwareRows(tx *gorm.DB, tables []string, ids []int64) (map[string]int64, error) {
+	deleted := make(map[string]int64)
+	if len(ids) == 0 {
+		return deleted, nil
+	}
+	for _, table := range tables {
+		// GORM menangani quoting nama tabel dinamis secara aman
+		res := tx.Table(table).Where("HARDWARE_ID IN ?", ids).Delete(nil)
+		if res.Error != nil {
+			return nil, fmt.Errorf("gagal menghapus dari tabel %s: %v", table, res.Error)
+		}
+		if res.RowsAffected > 0 {
+			deleted[table] = res.RowsAffected
+		}
+	}
+	res := tx.Table("hardware").Where("id IN ?", ids).Delete(nil)
+	if res.Error != nil {
+		return nil, fmt.Errorf("gagal menghapus hardware: %v", res.Error)
+	}
+	deleted["hardware"] += res.RowsAffected
+	return deleted, nil
+}
+
+// HardwareSelector memilih baris hardware; semua field yang diisi harus cocok
+type HardwareSelector struct {
+	ID       int64  `json:"id,omitempty"`
+	DeviceID string `json:"deviceid,omitempty"`
+	Name     string `json:"name,omitempty"`
+}
+
+// Empty bernilai true jika tidak ada kriteria yang diisi
+func (s HardwareSelector) Empty() bool {
+	return s.ID == 0 && s.DeviceID == "" && s.Name == ""
+}
+
+// normalizeHardwareName adalah kunci nama hardware untuk pencarian dan merge duplikat.
+// Sama dengan normalisasi di sisi sync (parser.NormalizeComputerName), dalam huruf besar.
+func normalizeHardwareName(name string) string {
+	return strings.ToUpper(parser.NormalizeComputerName(name))
+}
+
+// FindHardware mengembalikan baris hardware yang cocok dengan selector, maksimal limit baris.
+// Nama dicocokkan dengan normalizeHardwareName di Go, tidak bergantung collation tabel.
+func FindHardware(db *gorm.DB, sel HardwareSelector, limit int) ([]HardwareRecord, error) {
+	type hardwareRow struct {
+		ID       int64      `gorm:"column:ID"`
+		Name     string     `gorm:"column:NAME"`
+		DeviceID string     `gorm:"column:DEVICEID"`
+		IPAddr   string     `gorm:"column:IPADDR"`
+		LastDate *time.Time `gorm:"column:LASTDATE"`
+		LastCome *time.Time `gorm:"column:LASTCOME"`
+	}
+	name := normalizeHardwareName(sel.Name)
+	if sel.Name != "" && name == "" {
+		return []HardwareRecord{}, nil
+	}
+	q := db.Table("hardware").Select("ID, NAME, DEVICEID, IPADDR, LASTDATE, LASTCOME")
+	if sel.ID != 0 {
+		q = q.Where("ID = ?", sel.ID)
+	}
+	if sel.DeviceID != "" {
+		q = q.Where("DEVICEID = ?", sel.DeviceID)
+	}
+	q = q.Order("LASTDATE DESC, ID DESC")
+	// Dengan nama, limit baru diterapkan setelah penyaringan nama
+	if sel.Name == "" {
+		q = q.Limit(limit)
+	}
+	var rows []hardwareRow
+	if err := q.Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("gagal mencari hardware: %v", err)
+	}
+	records := make([]HardwareRecord, 0, len(rows))
+	for _, r := range rows {
+		if sel.Name != "" && normalizeHardwareName(r.Name) != name {
+			continue
+		}
+		if limit > 0 && len(records) == limit {
+			break
+		}
+		records = append(records, HardwareRecord{
+			ID:        r.ID,
+			Name:      r.Name,
+			DeviceID:  r.DeviceID,
+			IPAddress: r.IPAddr,
+			LastDate:  r.LastDate,
+			LastCome:  r.LastCome,
+		})
+	}
+	return records, nil
+}
@@ -0,0 +1,477 @@
+// client/ocs-hardware-merge.go
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"ocs-ad-inventorymanagement/parser"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Kriteria pencocokan hardware duplikat
+const (
+	MatchByName   = "name"
+	MatchBySerial = "serial"
+	MatchByMAC    = "mac"
+)
+
+// DefaultMatchBy dipakai jika kriteria tidak diisi
+var DefaultMatchBy = []string{MatchByName, MatchBySerial, MatchByMAC}
+
+// Serial dan MAC bawaan pabrik/virtual yang dipakai banyak perangkat, tidak boleh dipakai untuk mencocokkan
+var (
+	junkSerials = map[string]struct{}{
+		"": {}, "0": {}, "none": {}, "n/a": {}, "na": {}, "null": {}, "unknown": {}, "default string": {},
+		"to be filled by o.e.m.": {}, "system serial number": {}, "not specified": {}, "not applicable": {},
+		"0123456789": {}, "123456789": {}, "1234567890": {}, "chassis serial number": {}, "invalid": {},
+	}
+	junkMACs = map[string]struct{}{
+		"": {}, "00:00:00:00:00:00": {}, "ff:ff:ff:ff:ff:ff": {},
+	}
+)
+
+// maxSharedIdentity adalah batas jumlah baris yang boleh berbagi nama, serial atau MAC yang sama
+const maxSharedIdentity = 10
+
+// HardwareRecord adalah ringkasan satu baris hardware OCS untuk keperluan merge dan audit
+type HardwareRecord struct {
+	ID           int64      `json:"id"`
+	Name         string     `json:"name"`
+	DeviceID     string     `json:"device_id,omitempty"`
+	IPAddress    string     `json:"ip_address,omitempty"`
+	LastDate     *time.Time `json:"last_date"` // LASTDATE (inventaris terakhir)
+	LastCome     *time.Time `json:"last_come"`
+	Serial       string     `json:"serial,omitempty"`
+	MACAddresses []string   `json:"mac_addresses,omitempty"`
+}
+
+// DuplicateGroup adalah sekumpulan baris hardware yang dianggap satu perangkat.
+// Baris dengan LASTDATE terbaru dipertahankan, sisanya dihapus.
+type DuplicateGroup struct {
+	Keep      HardwareRecord   `json:"keep"`
+	Remove    []HardwareRecord `json:"remove"`
+	MatchedBy []string         `json:"matched_by"` // mis. "name:PC01" (nama ternormalisasi), "serial:ABC123"
+	Tables    map[string]int64 `json:"tables"`     // baris per tabel milik Remove (dry-run: akan dihapus)
+}
+
+// MergeOptions mengatur MergeHardwareDuplicates
+type MergeOptions struct {
+	MatchBy   []string // kombinasi MatchByName, MatchBySerial, MatchByMAC; kosong = semua
+	DryRun    bool     // hanya menghitung, tidak menghapus
+	Actor     string   // pelaku untuk audit, mis. username JWT atau "cli:<user>"
+	AuditFile string   // file JSON Lines untuk audit; kosong = default data/ocs-merge-audit.jsonl
+}
+
+// MergeResult adalah hasil merge (atau rencana merge saat dry-run)
+type MergeResult struct {
+	DryRun     bool             `json:"dry_run"`
+	MatchBy    []string         `json:"match_by"`
+	Groups     []DuplicateGroup `json:"groups"`
+	Review     []DuplicateGroup `json:"review"` // grup yang tersambung lintas kriteria, tidak dihapus otomatis
+	RemovedIDs int              `json:"removed_ids"`
+	Tables     map[string]int64 `json:"tables"` // total baris per tabel
+}
+
+// mergeAuditRecord adalah satu baris di file audit
+type mergeAuditRecord struct {
+	Time  time.Time `json:"time"`
+	Actor string    `json:"actor"`
+	MergeResult
+}
+
+// ParseMatchBy memvalidasi kriteria pencocokan; kosong berarti semua kriteria
+func ParseMatchBy(values []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var out []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			part = strings.ToLower(strings.TrimSpace(part))
+			if part == "" || seen[part] {
+				continue
+			}
+			switch part {
+			case MatchByName, MatchBySerial, MatchByMAC:
+			default:
+				return nil, fmt.Errorf("kriteria %q tidak dikenal, gunakan name, serial atau mac", part)
+			}
+			seen[part] = true
+			out = append(out, part)
+		}
+	}
+	if len(out) == 0 {
+		return DefaultMatchBy, nil
+	}
+	return out, nil
+}
+
+// FindHardwareDuplicates mengelompokkan baris hardware yang memiliki nama, serial BIOS atau MAC yang sama.
+// Grup yang semua anggotanya berbagi minimal satu kunci yang sama dikembalikan di groups.
+// Grup yang hanya tersambung secara transitif lintas kriteria (A-B sama nama, B-C sama serial)
+// dikembalikan terpisah di review karena belum tentu satu perangkat.
+// Untuk MAC hanya MAC utama yang dipakai; serial dan MAC yang ada di blacklist OCS atau bawaan pabrik,
+// serta nama, serial dan MAC yang dimiliki lebih dari maxSharedIdentity baris diabaikan.
+func FindHardwareDuplicates(db *gorm.DB, matchBy []string) (groups, review []DuplicateGroup, err error) {
+	var records []HardwareRecord
+	err = parser.ListOCSComputersFunc(db, 0, func(row parser.OCSComputerRow) error {
+		records = append(records, HardwareRecord{
+			ID:           row.ID,
+			Name:         row.ComputerName,
+			DeviceID:     row.DeviceID,
+			IPAddress:    row.IPAddress,
+			LastDate:     timePtr(row.OCSLastInventory),
+			LastCome:     timePtr(row.OCSLastCome),
+			Serial:       row.BIOSSerial,
+			MACAddresses: row.MACAddresses,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("gagal membaca hardware OCS: %v", err)
+	}
+
+	blacklistSerials := readBlacklist(db, "blacklist_serials", "SERIAL")
+	blacklistMACs := readBlacklist(db, "blacklist_macaddresses", "MACADDRESS")
+
+	// Union-find atas indeks records
+	parent := make([]int, len(records))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	criteria := make(map[string]bool)
+	for _, m := range matchBy {
+		criteria[m] = true
+	}
+	keysOf := make([][]string, len(records)) // indeks -> kunci miliknya
+	holders := make(map[string][]int)        // kunci -> indeks yang memilikinya
+	for i, r := range records {
+		var keys []string
+		if criteria[MatchByName] {
+			if name := normalizeHardwareName(r.Name); name != "" {
+				keys = append(keys, MatchByName+":"+name)
+			}
+		}
+		if criteria[MatchBySerial] {
+			serial := strings.TrimSpace(r.Serial)
+			norm := strings.ToLower(serial)
+			if _, junk := junkSerials[norm]; !junk && !blacklistSerials[norm] {
+				keys = append(keys, MatchBySerial+":"+serial)
+			}
+		}
+		// Hanya MAC utama (interface dengan IPADDR) agar adapter virtual/VPN yang sama
+		// di banyak komputer tidak menyatukan perangkat yang berbeda
+		if criteria[MatchByMAC] && len(r.MACAddresses) > 0 {
+			norm := strings.ToLower(strings.TrimSpace(r.MACAddresses[0]))
+			if _, junk := junkMACs[norm]; !junk && !blacklistMACs[norm] {
+				keys = append(keys, MatchByMAC+":"+strings.ToUpper(norm))
+			}
+		}
+		keysOf[i] = keys
+		for _, key := range keys {
+			holders[key] = append(holders[key], i)
+		}
+	}
+
+	shared := make(map[string]bool) // kunci yang dipakai untuk menyatukan baris
+	for key, idx := range holders {
+		if len(idx) < 2 {
+			continue
+		}
+		// Nama/serial/MAC yang dipakai terlalu banyak baris hampir pasti bukan identitas unik
+		// (mis. nama template image atau serial bawaan yang belum masuk junkSerials)
+		if len(idx) > maxSharedIdentity {
+			log.Printf("[WARN] OCS - %s dimiliki %d baris hardware, diabaikan untuk pencocokan duplikat", key, len(idx))
+			continue
+		}
+		shared[key] = true
+		for _, i := range idx[1:] {
+			parent[find(i)] = find(idx[0])
+		}
+	}
+
+	members := make(map[int][]int)
+	for i := range records {
+		root := find(i)
+		members[root] = append(members[root], i)
+	}
+
+	for _, idx := range members {
+		if len(idx) < 2 {
+			continue
+		}
+		sort.Slice(idx, func(a, b int) bool { return newerHardware(records[idx[a]], records[idx[b]]) })
+		g := DuplicateGroup{Keep: records[idx[0]]}
+		matched := make(map[string]int) // kunci -> jumlah anggota yang memilikinya
+		for n, i := range idx {
+			if n > 0 {
+				g.Remove = append(g.Remove, records[i])
+			}
+			for _, key := range keysOf[i] {
+				if shared[key] {
+					matched[key]++
+				}
+			}
+		}
+		common := false
+		for key, n := range matched {
+			g.MatchedBy = append(g.MatchedBy, key)
+			if n == len(idx) {
+				common = true
+			}
+		}
+		sort.Strings(g.MatchedBy)
+		if common {
+			groups = append(groups, g)
+		} else {
+			review = append(review, g)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Keep.ID < groups[j].Keep.ID })
+	sort.Slice(review, func(i, j int) bool { return review[i].Keep.ID < review[j].Keep.ID })
+	return groups, review, nil
+}
+
+// MergeHardwareDuplicates mencari grup duplikat, mempertahankan baris dengan LASTDATE terbaru
+// dan menghapus sisanya dari semua tabel ber-HARDWARE_ID dalam satu transaksi.
+// Grup yang perlu ditinjau manual (lihat FindHardwareDuplicates) hanya dilaporkan di Review.
+// Dengan DryRun hanya jumlah baris per tabel yang dihitung.
+// Merge yang benar-benar menghapus dicatat ke file audit (JSON Lines) setelah transaksi berhasil.
+func MergeHardwareDuplicates(db *gorm.DB, opts MergeOptions) (MergeResult, error) {
+	matchBy := opts.MatchBy
+	if len(matchBy) == 0 {
+		matchBy = DefaultMatchBy
+	}
+	result := MergeResult{DryRun: opts.DryRun, MatchBy: matchBy, Tables: map[string]int64{}}
+
+	tables, err := HardwareTables(db)
+	if err != nil {
+		return result, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		groups, review, err := FindHardwareDuplicates(tx, matchBy)
+		if err != nil {
+			return err
+		}
+		if !opts.DryRun {
+			if err := lockHardwareGroups(tx, groups); err != nil {
+				return err
+			}
+		}
+		result.Review = review
+		var ids []int64
+		for _, g := range groups {
+			for _, r := range g.Remove {
+				ids = append(ids, r.ID)
+			}
+		}
+		counts, err := countRowsByHardware(tx, tables, ids)
+		if err != nil {
+			return err
+		}
+		for i := range groups {
+			groups[i].Tables = make(map[string]int64)
+			for _, r := range groups[i].Remove {
+				for table, n := range counts[r.ID] {
+					groups[i].Tables[table] += n
+					result.Tables[table] += n
+				}
+			}
+		}
+		result.Groups = groups
+		result.RemovedIDs = len(ids)
+
+		if opts.DryRun || len(ids) == 0 {
+			return nil
+		}
+		deleted, err := DeleteHardwareRows(tx, tables, ids)
+		if err != nil {
+			return err
+		}
+		// Total sebenarnya dari RowsAffected, bisa berbeda tipis dari hitungan jika ada baris tanpa hardware
+		result.Tables = deleted
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if !opts.DryRun && result.RemovedIDs > 0 {
+		if err := appendMergeAudit(opts.AuditFile, mergeAuditRecord{Time: time.Now().UTC(), Actor: opts.Actor, MergeResult: result}); err != nil {
+			return result, fmt.Errorf("hardware duplikat sudah dihapus tetapi audit gagal ditulis: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// lockHardwareGroups mengunci baris hardware anggota groups dengan FOR UPDATE.
+// SELECT biasa di FindHardwareDuplicates tidak mengunci apa pun, jadi setelah dikunci
+// LASTDATE/LASTCOME dibandingkan lagi; jika ada agent yang melapor atau baris yang sudah
+// terhapus di antaranya, merge dibatalkan karena baris yang dipertahankan bisa berbeda.
+func lockHardwareGroups(tx *gorm.DB, groups []DuplicateGroup) error {
+	expected := make(map[int64]HardwareRecord)
+	var ids []int64
+	for _, g := range groups {
+		for _, r := range append([]HardwareRecord{g.Keep}, g.Remove...) {
+			expected[r.ID] = r
+			ids = append(ids, r.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	type lockedRow struct {
+		ID       int64      `gorm:"column:ID"`
+		LastDate *time.Time `gorm:"column:LASTDATE"`
+		LastCome *time.Time `gorm:"column:LASTCOME"`
+	}
+	var rows []lockedRow
+	// Urutan ID yang tetap mencegah deadlock dengan transaksi lain yang mengunci baris yang sama
+	err := tx.Table("hardware").
+		Select("ID, LASTDATE, LASTCOME").
+		Where("ID IN ?", ids).
+		Order("ID").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Scan(&rows).Error
+	if err != nil {
+		return fmt.Errorf("gagal mengunci baris hardware: %v", err)
+	}
+	if len(rows) != len(ids) {
+		return fmt.Errorf("sebagian hardware sudah berubah selama merge (%d dari %d baris), ulangi merge", len(rows), len(ids))
+	}
+	for _, row := range rows {
+		r := expected[row.ID]
+		if compareTime(r.LastDate, row.LastDate) != 0 || compareTime(r.LastCome, row.LastCome) != 0 {
+			return fmt.Errorf("hardware ID %d melapor selama merge, ulangi merge", row.ID)
+		}
+	}
+	return nil
+}
+
+// countRowsByHardware menghitung baris per HARDWARE_ID per tabel, termasuk baris hardware itu sendiri
+func countRowsByHardware(db *gorm.DB, tables []string, ids []int64) (map[int64]map[string]int64, error) {
+	counts := make(map[int64]map[string]int64)
+	if len(ids) == 0 {
+		return counts, nil
+	}
+	type countRow struct {
+		HardwareID int64 `gorm:"column:HARDWARE_ID"`
+		N          int64 `gorm:"column:N"`
+	}
+	add := func(table string, rows []countRow) {
+		for _, r := range rows {
+			if counts[r.HardwareID] == nil {
+				counts[r.HardwareID] = make(map[string]int64)
+			}
+			counts[r.HardwareID][table] += r.N
+		}
+	}
+	for _, table := range tables {
+		var rows []countRow
+		err := db.Table(table).
+			Select("HARDWARE_ID, COUNT(*) AS N").
+			Where("HARDWARE_ID IN ?", ids).
+			Group("HARDWARE_ID").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, fmt.Errorf("gagal menghitung baris tabel %s: %v", table, err)
+		}
+		add(table, rows)
+	}
+	var rows []countRow
+	if err := db.Table("hardware").Select("ID AS HARDWARE_ID, 1 AS N").Where("ID IN ?", ids).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("gagal menghitung baris hardware: %v", err)
+	}
+	add("hardware", rows)
+	return counts, nil
+}
+
+// readBlacklist membaca satu kolom tabel blacklist OCS (huruf kecil).
+// Tabel yang tidak ada di versi OCS lama hanya menghasilkan peringatan.
+func readBlacklist(db *gorm.DB, table, column string) map[string]bool {
+	var values []string
+	if err := db.Table(table).Pluck(column, &values).Error; err != nil {
+		log.Printf("[WARN] OCS - Gagal membaca %s, blacklist diabaikan: %v", table, err)
+		return nil
+	}
+	out := make(map[string]bool, len(values))
+	for _, v := range values {
+		out[strings.ToLower(strings.TrimSpace(v))] = true
+	}
+	return out
+}
+
+// newerHardware mengurutkan LASTDATE terbaru dulu, lalu LASTCOME, lalu ID terbesar
+func newerHardware(a, b HardwareRecord) bool {
+	if c := compareTime(a.LastDate, b.LastDate); c != 0 {
+		return c > 0
+	}
+	if c := compareTime(a.LastCome, b.LastCome); c != 0 {
+		return c > 0
+	}
+	return a.ID > b.ID
+}
+
+// compareTime membandingkan dua waktu; nil dianggap paling lama
+func compareTime(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return a.Compare(*b)
+}
+
+// timePtr mengembalikan nil untuk waktu kosong
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// appendMergeAudit menambahkan satu baris JSON ke file audit
+func appendMergeAudit(file string, rec mergeAuditRecord) error {
+	if file == "" {
+		file = DefaultMergeAuditFile
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		// Jangan sampai catatan hilang: tulis ke log sebagai cadangan
+		log.Printf("[ERROR] OCS - Audit merge tidak tersimpan, isi audit: %s", b)
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		log.Printf("[ERROR] OCS - Audit merge tidak tersimpan, isi audit: %s", b)
+		return err
+	}
+	return nil
+}
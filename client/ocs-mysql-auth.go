@@ -3,7 +3,9 @@ package client
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -16,8 +18,17 @@ type OCSConfig struct {
 	DBName string
 	DBUser string
 	DBPass string
+	// Zona waktu kolom DATETIME di database OCS, mis. "Asia/Jakarta" (default Local)
+	DBTimezone string
+	// Jumlah baris hardware per halaman saat membaca OCS (0 = default)
+	PageSize int
+	// File audit merge hardware duplikat (JSON Lines)
+	MergeAuditFile string
 }
 
+// DefaultMergeAuditFile adalah lokasi audit merge jika OCS_MERGE_AUDIT_FILE kosong
+var DefaultMergeAuditFile = filepath.Join("data", "ocs-merge-audit.jsonl")
+
 // LoadOCSConfig membaca konfigurasi dari environment
 func LoadOCSConfig() OCSConfig {
 	return OCSConfig{
@@ -26,6 +37,11 @@ func LoadOCSConfig() OCSConfig {
 		DBName: os.Getenv("OCS_DB_NAME"),
 		DBUser: os.Getenv("OCS_DB_USER"),
 		DBPass: os.Getenv("OCS_DB_PASS"),
+
+		DBTimezone: os.Getenv("OCS_DB_TZ"),
+		PageSize:   envInt("OCS_PAGE_SIZE", 0),
+
+		MergeAuditFile: envString("OCS_MERGE_AUDIT_FILE", DefaultMergeAuditFile),
 	}
 }
 
@@ -37,8 +53,12 @@ type OCSMySQLClient struct {
 
 // NewOCSMySQLClient membuat client baru dan mencoba koneksi ke OCS MySQL
 func NewOCSMySQLClient(cfg OCSConfig) (*OCSMySQLClient, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		cfg.DBUser, cfg.DBPass, cfg.DBUrl, cfg.DBPort, cfg.DBName)
+	loc := cfg.DBTimezone
+	if loc == "" {
+		loc = "Local"
+	}
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=%s",
+		cfg.DBUser, cfg.DBPass, cfg.DBUrl, cfg.DBPort, cfg.DBName, url.QueryEscape(loc))
 	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
 	if err != nil {
 		return nil, err
@@ -1,16 +1,22 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
+	_ "time/tzdata" // Database zona waktu tertanam untuk DISPLAY_TZ / OCS_DB_TZ di image minimal
 
 	"ocs-ad-inventorymanagement/api"
 	"ocs-ad-inventorymanagement/client"
+	"ocs-ad-inventorymanagement/notify"
 	"ocs-ad-inventorymanagement/parser"
+	"ocs-ad-inventorymanagement/sync"
 	"ocs-ad-inventorymanagement/web"
 
 	"github.com/gin-gonic/gin"
@@ -21,14 +27,31 @@ func main() {
 	// Memuat file .env, tidak akan error jika file tidak ada
 	godotenv.Load()
 
-	// 1. Muat konfigurasi LDAP dan konek
-	ldapCfg := client.LoadLDAPConfig()
-	ldapClient, err := client.NewLDAPClient(ldapCfg)
+	// Subcommand CLI, mis. "merge-ocs-duplicates"; tanpa argumen berjalan sebagai service
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	// Zona waktu untuk field tanggal di Elasticsearch (default WIB)
+	if err := parser.SetDisplayTimezone(os.Getenv("DISPLAY_TZ")); err != nil {
+		log.Fatalf("[FATAL] Konfigurasi DISPLAY_TZ tidak valid: %v", err)
+	}
+	stalenessPolicy, err := parser.LoadStalenessPolicy()
 	if err != nil {
-		log.Fatalf("[FATAL] Gagal koneksi ke LDAP: %v", err)
+		log.Fatalf("[FATAL] Konfigurasi aturan staleness tidak valid: %v", err)
+	}
+	parser.SetStalenessPolicy(stalenessPolicy)
+
+	// 1. Muat konfigurasi LDAP (satu atau beberapa domain) dan konek
+	adSources := sync.NewMultiADSource(client.LoadLDAPConfigs())
+	for _, src := range adSources {
+		if err := src.Connect(); err != nil {
+			// Tidak fatal: koneksi akan dicoba ulang di setiap siklus sinkronisasi
+			log.Printf("[ERROR] Gagal koneksi ke LDAP, akan dicoba ulang saat sinkronisasi: %v", err)
+			continue
+		}
+		log.Printf("[SUCCESS] LDAP - Berhasil konek dan autentikasi ke Active Directory via %s.", src.Client.Server)
 	}
-	defer ldapClient.Close()
-	log.Println("[SUCCESS] LDAP - Berhasil konek dan autentikasi ke Active Directory.")
 
 	// 2. Koneksi ke OCS MySQL (tetap sama)
 	ocsCfg := client.LoadOCSConfig()
@@ -41,7 +64,55 @@ func main() {
 	}
 	log.Println("[SUCCESS] OCS - Berhasil konek ke database.")
 
-	// 3. Jalankan Web API (tetap sama)
+	// 3. Siapkan client Elasticsearch dan Syncer
+	esCfg := client.LoadElasticsearchConfig()
+	esClient, err := client.NewElasticsearchClient(esCfg)
+	if err != nil {
+		log.Fatalf("[FATAL] Gagal membuat client Elasticsearch: %v", err)
+	}
+
+	esSink := sync.NewElasticsearchSink(esClient)
+	mappingCtx, cancelMapping := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := esSink.EnsureMapping(mappingCtx); err != nil {
+		// Tidak fatal: template akan dicoba dipasang lagi sebelum indexing
+		log.Printf("[ERROR] Elasticsearch - Gagal memasang index template: %v", err)
+	}
+	cancelMapping()
+
+	var sink sync.Sink = esSink
+	if esCfg.VersionedIndices {
+		// Setiap siklus dibangun di index baru lalu alias dipindahkan setelah selesai
+		sink = sync.NewVersionedElasticsearchSink(esSink)
+		log.Printf("[INFO] Elasticsearch - Mode index berversi aktif, alias %s, simpan %d generasi lama", esCfg.Index, esCfg.RetainGenerations)
+	}
+
+	syncer := sync.NewSyncer(
+		adSources,
+		&sync.OCSDBSource{DB: ocsClient.DB, PageSize: ocsCfg.PageSize},
+		sink,
+	)
+	syncer.Events = sync.LogEventSink{}
+	if notifyCfg := notify.LoadConfig(); notifyCfg.Enabled() {
+		notifier, err := notify.NewNotifier(notifyCfg)
+		if err != nil {
+			log.Fatalf("[FATAL] Konfigurasi notifikasi tidak valid: %v", err)
+		}
+		syncer.Notifier = notifier
+		log.Printf("[INFO] Notifikasi aktif untuk aturan %s ke %d channel", notifyCfg.Rules, len(notifier.Channels))
+	}
+	if esCfg.HistoryStream != "" {
+		syncer.History = sync.NewElasticsearchHistory(esClient)
+		log.Printf("[INFO] Elasticsearch - Riwayat harian ke data stream %s, retensi %s", esCfg.HistoryStream, esCfg.HistoryRetention)
+	}
+
+	schedCfg := sync.LoadScheduleConfig()
+	schedule, err := schedCfg.Schedule()
+	if err != nil {
+		log.Fatalf("[FATAL] Konfigurasi jadwal sinkronisasi tidak valid: %v", err)
+	}
+	scheduler := sync.NewScheduler(syncer, schedule, schedCfg)
+
+	// 4. Jalankan Web API
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
 
@@ -59,6 +130,9 @@ func main() {
 	apiGroup := r.Group(basePath + "/api")
 	apiGroup.POST("/auth-token", api.AuthTokenHandler)
 	apiGroup.POST("/delete-computer", api.DeleteComputerHandler(ocsClient.DB))
+	apiGroup.POST("/ocs/merge-duplicates", api.MergeDuplicatesHandler(ocsClient.DB, ocsCfg.MergeAuditFile))
+	apiGroup.POST("/sync/run", api.SyncRunHandler(scheduler))
+	apiGroup.GET("/sync/status", api.SyncStatusHandler(scheduler))
 
 	r.GET(basePath+"/delete-computer", func(c *gin.Context) {
 		c.Header("Content-Type", "text/html; charset=utf-8")
@@ -70,187 +144,44 @@ func main() {
 		port = "8080"
 	}
 	addr := ":" + port
+	srv := &http.Server{Addr: addr, Handler: r}
 	go func() {
 		log.Printf("[INFO] Web server berjalan di alamat %s%s", addr, basePath)
-		if err := r.Run(addr); err != nil {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("[FATAL] Gagal menjalankan web API: %v", err)
 		}
 	}()
 
-	// 4. Scheduler utama untuk sinkronisasi data
-	for {
-		// --- Ambil data dari AD via LDAP ---
-		ldapEntries, err := ldapClient.ListComputers()
-		if err != nil {
-			// Jika error, coba re-koneksi sekali sebelum gagal
-			log.Printf("[ERROR] Gagal mengambil data dari LDAP: %v. Mencoba re-koneksi...", err)
-			ldapClient.Close()
-			ldapClient, err = client.NewLDAPClient(ldapCfg)
-			if err != nil {
-				log.Fatalf("[FATAL] Gagal re-koneksi ke LDAP: %v", err)
-			}
-			ldapEntries, err = ldapClient.ListComputers()
-			if err != nil {
-				log.Fatalf("[FATAL] Gagal mengambil data dari LDAP setelah re-koneksi: %v", err)
-			}
-		}
-
-		// --- Parse data AD ---
-		cleanData, err := parser.ParseComputerReportFromLDAP(ldapEntries)
-		if err != nil {
-			log.Fatalf("Proses transformasi data AD gagal: %v", err)
-		}
-		log.Printf("[SUCCESS] LDAP - Data berhasil diparsing, Total: %d", len(cleanData))
-
-		// --- Ambil data dari OCS ---
-		ocsComputers, err := parser.ListOCSComputers(ocsClient.DB, 0)
-		if err != nil {
-			log.Printf("[ERROR] Gagal mengambil data komputer OCS: %v", err)
-			continue
-		}
-		log.Printf("[SUCCESS] OCS - Data berhasil diparsing, Total: %d", len(ocsComputers))
-
-		// --- Gabungkan data OCS dan AD ---
-		finalList := parser.CombineOCSAndAD(ocsComputers, cleanData)
-		log.Printf("[SUCCESS] OCS x AD - Data digabungkan, Total: %d", len(finalList))
-
-		// --- Simpan ke Elasticsearch ---
-		esCfg := client.LoadElasticsearchConfig()
-		esClient, err := client.NewElasticsearchClient(esCfg)
-		if err != nil {
-			log.Printf("[ERROR] Gagal membuat client Elasticsearch: %v", err)
-			continue
-		}
-
-		// --- Sinkronisasi: hapus data yang sudah tidak ada di OCS/AD ---
-		cacheOCS := make(map[string]struct{})
-		cacheAD := make(map[string]struct{})
-		hashName := func(name string) string {
-			return parser.HashComputerName(name)
-		}
-		for _, ocs := range ocsComputers {
-			cacheOCS[hashName(ocs.ComputerName)] = struct{}{}
-		}
-		for _, ad := range cleanData {
-			cacheAD[hashName(ad.ComputerName)] = struct{}{}
-		}
-
-		var esIDs []string
-		from := 0
-		size := 10000
-		for {
-			query := `{"query":{"match_all":{}},"_source":false,"from":` + fmt.Sprintf("%d", from) + `,"size":` + fmt.Sprintf("%d", size) + `}`
-			res, err := esClient.Client.Search(
-				esClient.Client.Search.WithIndex(esCfg.Index),
-				esClient.Client.Search.WithBody(strings.NewReader(query)),
-			)
-			if err != nil {
-				log.Printf("[ERROR] Gagal mengambil document ID dari Elasticsearch: %v", err)
-				break
-			}
-			defer res.Body.Close()
-			var resp struct {
-				Hits struct {
-					Hits []struct {
-						ID string `json:"_id"`
-					} `json:"hits"`
-				} `json:"hits"`
-			}
-			if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
-				log.Printf("[ERROR] Gagal decode response Elasticsearch: %v", err)
-				break
-			}
-			if len(resp.Hits.Hits) == 0 {
-				break
-			}
-			for _, hit := range resp.Hits.Hits {
-				esIDs = append(esIDs, hit.ID)
-			}
-			if len(resp.Hits.Hits) < size {
-				break
-			}
-			from += size
-		}
+	// 5. Scheduler utama untuk sinkronisasi data, berhenti saat SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-		deleted := 0
-		for _, id := range esIDs {
-			h := hashName(id)
-			_, existsOCS := cacheOCS[h]
-			_, existsAD := cacheAD[h]
-			if !existsOCS && !existsAD {
-				res, err := esClient.Client.Delete(esCfg.Index, id)
-				if err != nil {
-					log.Printf("[ERROR] Gagal hapus document %s di Elasticsearch: %v", id, err)
-					continue
-				}
-				if res.IsError() {
-					log.Printf("[ERROR] Elasticsearch response error saat hapus %s: %s", id, res.String())
-				} else {
-					deleted++
-				}
-				res.Body.Close()
-			}
-		}
-		log.Printf("[INFO] Elasticsearch - Hapus data lama, Total: %d", deleted)
+	log.Printf("[INFO] Scheduler sinkronisasi berjalan %s", schedCfg.Describe())
+	scheduler.Run(ctx)
 
-		// --- Index/update data yang masih ada ---
-		batchSize := 500
-		maxParallel := 4
-		type indexResult struct {
-			success int
-			failed  int
-		}
-		indexBatch := func(batch []parser.FinalComputerRow, ch chan<- indexResult) {
-			success, failed := 0, 0
-			for _, row := range batch {
-				docID := row.ComputerName
-				body, _ := json.Marshal(row)
-				res, err := esClient.Client.Index(esCfg.Index, strings.NewReader(string(body)), esClient.Client.Index.WithDocumentID(docID))
-				if err != nil {
-					log.Printf("[ERROR] Indexing gagal untuk %s: %v", docID, err)
-					failed++
-					continue
-				}
-				if res.IsError() {
-					log.Printf("[ERROR] Elasticsearch response error untuk %s: %s", docID, res.String())
-					failed++
-				} else {
-					success++
-				}
-				res.Body.Close()
-			}
-			ch <- indexResult{success, failed}
-		}
-
-		var batches [][]parser.FinalComputerRow
-		for i := 0; i < len(finalList); i += batchSize {
-			end := i + batchSize
-			if end > len(finalList) {
-				end = len(finalList)
-			}
-			batches = append(batches, finalList[i:end])
-		}
+	// 6. Graceful shutdown: tunggu request dan siklus yang sedang berjalan hingga batas waktu
+	drainTimeout := shutdownTimeout()
+	log.Printf("[INFO] Sinyal shutdown diterima, menunggu proses berjalan selesai (maks %s)...", drainTimeout)
+	drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
 
-		ch := make(chan indexResult, len(batches))
-		sem := make(chan struct{}, maxParallel)
-
-		for _, batch := range batches {
-			sem <- struct{}{} // acquire
-			go func(b []parser.FinalComputerRow) {
-				defer func() { <-sem }() // release
-				indexBatch(b, ch)
-			}(batch)
-		}
+	if err := srv.Shutdown(drainCtx); err != nil {
+		log.Printf("[ERROR] Web server tidak berhenti dengan bersih: %v", err)
+	}
+	if err := scheduler.Shutdown(drainCtx); err != nil {
+		log.Printf("[ERROR] Siklus sinkronisasi dibatalkan saat shutdown: %v", err)
+	}
+	adSources.Close()
+	log.Println("[INFO] Aplikasi berhenti.")
+}
 
-		success, failed := 0, 0
-		for i := 0; i < len(batches); i++ {
-			res := <-ch
-			success += res.success
-			failed += res.failed
+// shutdownTimeout membaca SHUTDOWN_TIMEOUT (mis. "30s"), default 30 detik
+func shutdownTimeout() time.Duration {
+	if s := os.Getenv("SHUTDOWN_TIMEOUT"); s != "" {
+		if d, err := time.ParseDuration(s); err == nil && d > 0 {
+			return d
 		}
-		log.Printf("[INFO] Elasticsearch - Indexing selesai. Sukses: %d, Gagal: %d", success, failed)
-
-		log.Println("----------------- Siklus Selesai, Menunggu 60 Detik -----------------")
-		time.Sleep(60 * time.Second)
+		log.Printf("[WARN] Nilai SHUTDOWN_TIMEOUT=%q tidak valid, memakai default 30s", s)
 	}
+	return 30 * time.Second
 }
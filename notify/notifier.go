@@ -0,0 +1,325 @@
+package notify
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"ocs-ad-inventorymanagement/parser"
+)
+
+const (
+	defaultSubjectTemplate = `[Inventaris] {{.Total}} temuan baru`
+	defaultBodyTemplate    = `{{.Total}} temuan inventaris baru pada {{.Time.Format "2006-01-02 15:04 MST"}}:
+{{range .Alerts}}- [{{.Rule}}] {{.ComputerName}}{{if .Domain}} ({{.Domain}}){{end}}: {{.Description}}
+{{end}}{{if .Omitted}}... dan {{.Omitted}} temuan lainnya
+{{end}}`
+)
+
+// Config menyimpan konfigurasi notifikasi dari environment
+type Config struct {
+	Rules           string        // NOTIFY_RULES, nama aturan dipisah koma
+	WebhookURLs     []string      // NOTIFY_WEBHOOK_URLS, dipisah koma
+	SMTPHost        string        // NOTIFY_SMTP_HOST
+	SMTPPort        string        // NOTIFY_SMTP_PORT, default 25
+	SMTPUser        string        // NOTIFY_SMTP_USER
+	SMTPPass        string        // NOTIFY_SMTP_PASS
+	SMTPFrom        string        // NOTIFY_SMTP_FROM
+	SMTPTo          []string      // NOTIFY_SMTP_TO, dipisah koma
+	SubjectTemplate string        // NOTIFY_SUBJECT_TEMPLATE (text/template)
+	BodyTemplate    string        // NOTIFY_BODY_TEMPLATE (text/template)
+	MaxItems        int           // NOTIFY_MAX_ITEMS, jumlah temuan maksimum per pesan, default 50
+	RepeatAfter     time.Duration // NOTIFY_REPEAT_AFTER, kirim ulang temuan yang masih berlaku; 0 = tidak pernah
+	StateFile       string        // NOTIFY_STATE_FILE, default data/notify-state.json
+}
+
+// LoadConfig memuat konfigurasi notifikasi dari environment variables
+func LoadConfig() Config {
+	cfg := Config{
+		Rules:           os.Getenv("NOTIFY_RULES"),
+		WebhookURLs:     splitComma(os.Getenv("NOTIFY_WEBHOOK_URLS")),
+		SMTPHost:        os.Getenv("NOTIFY_SMTP_HOST"),
+		SMTPPort:        os.Getenv("NOTIFY_SMTP_PORT"),
+		SMTPUser:        os.Getenv("NOTIFY_SMTP_USER"),
+		SMTPPass:        os.Getenv("NOTIFY_SMTP_PASS"),
+		SMTPFrom:        os.Getenv("NOTIFY_SMTP_FROM"),
+		SMTPTo:          splitComma(os.Getenv("NOTIFY_SMTP_TO")),
+		SubjectTemplate: os.Getenv("NOTIFY_SUBJECT_TEMPLATE"),
+		BodyTemplate:    os.Getenv("NOTIFY_BODY_TEMPLATE"),
+		MaxItems:        50,
+		StateFile:       os.Getenv("NOTIFY_STATE_FILE"),
+	}
+	if cfg.SMTPPort == "" {
+		cfg.SMTPPort = "25"
+	}
+	if n, err := strconv.Atoi(os.Getenv("NOTIFY_MAX_ITEMS")); err == nil && n > 0 {
+		cfg.MaxItems = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("NOTIFY_REPEAT_AFTER")); err == nil {
+		cfg.RepeatAfter = d
+	}
+	if cfg.StateFile == "" {
+		cfg.StateFile = filepath.Join("data", "notify-state.json")
+	}
+	return cfg
+}
+
+// Enabled bernilai true jika ada aturan dan minimal satu channel tujuan
+func (c Config) Enabled() bool {
+	return strings.TrimSpace(c.Rules) != "" && (len(c.WebhookURLs) > 0 || (c.SMTPHost != "" && len(c.SMTPTo) > 0))
+}
+
+// Alert adalah satu komputer yang cocok dengan satu aturan
+type Alert struct {
+	Rule         string
+	Description  string
+	DocumentID   string
+	ComputerName string
+	Domain       string
+	Row          parser.FinalComputerRow
+}
+
+// Message adalah pesan yang sudah dirender dari template
+type Message struct {
+	Subject string
+	Body    string
+}
+
+// templateData adalah data yang tersedia di NOTIFY_SUBJECT_TEMPLATE dan NOTIFY_BODY_TEMPLATE
+type templateData struct {
+	Time    time.Time
+	Total   int
+	Omitted int
+	Alerts  []Alert
+}
+
+// Channel adalah tujuan pengiriman notifikasi
+type Channel interface {
+	// Name dipakai sebagai kunci dedupe, harus stabil antar restart
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// Notifier mengevaluasi aturan setiap siklus dan hanya mengirim temuan yang belum pernah dikirim
+// ke channel tersebut. Temuan yang sudah tidak berlaku dilupakan, sehingga akan dikirim lagi jika muncul kembali.
+type Notifier struct {
+	Rules       []Rule
+	Channels    []Channel
+	MaxItems    int
+	RepeatAfter time.Duration
+	StateFile   string
+
+	subject *template.Template
+	body    *template.Template
+	sent    map[string]time.Time // "<channel>|<aturan>|<document ID>" -> waktu terakhir dikirim
+}
+
+// NewNotifier membangun Notifier dari konfigurasi
+func NewNotifier(cfg Config) (*Notifier, error) {
+	rules, err := ParseRules(cfg.Rules)
+	if err != nil {
+		return nil, err
+	}
+	subjectText := cfg.SubjectTemplate
+	if subjectText == "" {
+		subjectText = defaultSubjectTemplate
+	}
+	bodyText := cfg.BodyTemplate
+	if bodyText == "" {
+		bodyText = defaultBodyTemplate
+	}
+	subject, err := template.New("subject").Parse(subjectText)
+	if err != nil {
+		return nil, fmt.Errorf("NOTIFY_SUBJECT_TEMPLATE tidak valid: %v", err)
+	}
+	body, err := template.New("body").Parse(bodyText)
+	if err != nil {
+		return nil, fmt.Errorf("NOTIFY_BODY_TEMPLATE tidak valid: %v", err)
+	}
+
+	n := &Notifier{
+		Rules:       rules,
+		MaxItems:    cfg.MaxItems,
+		RepeatAfter: cfg.RepeatAfter,
+		StateFile:   cfg.StateFile,
+		subject:     subject,
+		body:        body,
+	}
+	for _, u := range cfg.WebhookURLs {
+		n.Channels = append(n.Channels, NewWebhookChannel(u))
+	}
+	if cfg.SMTPHost != "" && len(cfg.SMTPTo) > 0 {
+		n.Channels = append(n.Channels, &SMTPChannel{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUser,
+			Password: cfg.SMTPPass,
+			From:     cfg.SMTPFrom,
+			To:       cfg.SMTPTo,
+		})
+	}
+	return n, nil
+}
+
+// Evaluate mengembalikan semua temuan untuk baris yang diberikan, terurut per aturan lalu nama
+func (n *Notifier) Evaluate(rows []parser.FinalComputerRow) []Alert {
+	var alerts []Alert
+	for _, rule := range n.Rules {
+		for _, row := range rows {
+			if !rule.Match(row) {
+				continue
+			}
+			alerts = append(alerts, Alert{
+				Rule:         rule.Name,
+				Description:  rule.Description,
+				DocumentID:   row.DocumentID(),
+				ComputerName: row.ComputerName,
+				Domain:       row.ADDomain,
+				Row:          row,
+			})
+		}
+	}
+	sort.SliceStable(alerts, func(i, j int) bool {
+		if alerts[i].Rule != alerts[j].Rule {
+			return alerts[i].Rule < alerts[j].Rule
+		}
+		return alerts[i].DocumentID < alerts[j].DocumentID
+	})
+	return alerts
+}
+
+// Notify mengevaluasi aturan lalu mengirim temuan baru ke setiap channel.
+// Temuan ditandai terkirim per channel hanya jika pengiriman ke channel tersebut berhasil
+// dan temuan tersebut tercantum di pesan (tidak terpotong MaxItems).
+func (n *Notifier) Notify(ctx context.Context, rows []parser.FinalComputerRow) error {
+	n.loadState()
+	now := time.Now()
+	alerts := n.Evaluate(rows)
+
+	active := make(map[string]struct{}, len(alerts))
+	var errs []error
+	for _, ch := range n.Channels {
+		var pending []Alert
+		for _, a := range alerts {
+			key := ch.Name() + "|" + a.Rule + "|" + a.DocumentID
+			active[key] = struct{}{}
+			last, ok := n.sent[key]
+			if ok && (n.RepeatAfter <= 0 || now.Sub(last) < n.RepeatAfter) {
+				continue
+			}
+			pending = append(pending, a)
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		msg, err := n.render(pending, now)
+		if err != nil {
+			return err
+		}
+		if err := ch.Send(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ch.Name(), err))
+			continue
+		}
+		// Hanya temuan yang tercantum di pesan yang ditandai; sisanya dikirim di notifikasi berikutnya
+		shown := pending
+		if n.MaxItems > 0 && len(shown) > n.MaxItems {
+			shown = shown[:n.MaxItems]
+		}
+		for _, a := range shown {
+			n.sent[ch.Name()+"|"+a.Rule+"|"+a.DocumentID] = now
+		}
+		log.Printf("[INFO] Notifikasi - %d dari %d temuan baru dikirim ke %s", len(shown), len(pending), ch.Name())
+	}
+
+	// Lupakan temuan yang sudah tidak berlaku
+	for key := range n.sent {
+		if _, ok := active[key]; !ok {
+			delete(n.sent, key)
+		}
+	}
+	if err := n.saveState(); err != nil {
+		errs = append(errs, fmt.Errorf("gagal menyimpan state notifikasi: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// render membangun pesan dari template; daftar temuan dipotong hingga MaxItems
+func (n *Notifier) render(alerts []Alert, now time.Time) (Message, error) {
+	data := templateData{Time: now.In(parser.DisplayLocation()), Total: len(alerts), Alerts: alerts}
+	if n.MaxItems > 0 && len(alerts) > n.MaxItems {
+		data.Alerts = alerts[:n.MaxItems]
+		data.Omitted = len(alerts) - n.MaxItems
+	}
+	var subject, body strings.Builder
+	if err := n.subject.Execute(&subject, data); err != nil {
+		return Message{}, fmt.Errorf("gagal merender subject notifikasi: %v", err)
+	}
+	if err := n.body.Execute(&body, data); err != nil {
+		return Message{}, fmt.Errorf("gagal merender isi notifikasi: %v", err)
+	}
+	return Message{Subject: strings.TrimSpace(subject.String()), Body: body.String()}, nil
+}
+
+// loadState membaca daftar temuan yang sudah dikirim dari disk (sekali saja)
+func (n *Notifier) loadState() {
+	if n.sent != nil {
+		return
+	}
+	n.sent = make(map[string]time.Time)
+	b, err := os.ReadFile(n.StateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[WARN] Notifikasi - Gagal membaca state %s: %v", n.StateFile, err)
+		}
+		return
+	}
+	if err := json.Unmarshal(b, &n.sent); err != nil {
+		log.Printf("[WARN] Notifikasi - State %s rusak, diabaikan: %v", n.StateFile, err)
+		n.sent = make(map[string]time.Time)
+	}
+}
+
+// saveState menyimpan state ke disk secara atomik (tulis file sementara lalu rename)
+func (n *Notifier) saveState() error {
+	if err := os.MkdirAll(filepath.Dir(n.StateFile), 0o755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(n.sent)
+	if err != nil {
+		return err
+	}
+	tmp := n.StateFile + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, n.StateFile)
+}
+
+// splitComma memecah daftar dipisah koma dan membuang entri kosong
+func splitComma(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// shortHash mengembalikan 8 karakter pertama SHA-1 dari s
+func shortHash(s string) string {
+	h := sha1.Sum([]byte(s))
+	return hex.EncodeToString(h[:4])
+}
@@ -0,0 +1,370 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ocs-ad-inventorymanagement/parser"
+)
+
+// fakeWebhook adalah pengganti lokal incoming webhook; status bisa diubah untuk mensimulasikan gangguan
+type fakeWebhook struct {
+	mu       sync.Mutex
+	status   int
+	messages []string
+	srv      *httptest.Server
+}
+
+func newFakeWebhook(t *testing.T) *fakeWebhook {
+	t.Helper()
+	f := &fakeWebhook{status: http.StatusOK}
+	f.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.status != http.StatusOK {
+			w.WriteHeader(f.status)
+			return
+		}
+		f.messages = append(f.messages, body.Text)
+	}))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeWebhook) setStatus(status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status = status
+}
+
+// take mengembalikan pesan yang diterima sejak pemanggilan sebelumnya
+func (f *fakeWebhook) take() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	msgs := f.messages
+	f.messages = nil
+	return msgs
+}
+
+// fakeSMTP adalah server SMTP minimal (tanpa STARTTLS/AUTH) yang menyimpan isi DATA
+type fakeSMTP struct {
+	mu         sync.Mutex
+	rejectRcpt bool
+	messages   []string
+	port       string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	f := &fakeSMTP{port: strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+	reply("220 fake.smtp ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 fake.smtp")
+		case strings.HasPrefix(cmd, "MAIL FROM"):
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO"):
+			f.mu.Lock()
+			reject := f.rejectRcpt
+			f.mu.Unlock()
+			if reject {
+				reply("550 mailbox unavailable")
+			} else {
+				reply("250 OK")
+			}
+		case cmd == "DATA":
+			reply("354 end with .")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			f.mu.Lock()
+			f.messages = append(f.messages, data.String())
+			f.mu.Unlock()
+			reply("250 queued")
+		case cmd == "RSET", cmd == "NOOP":
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func (f *fakeSMTP) take() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	msgs := f.messages
+	f.messages = nil
+	return msgs
+}
+
+// newTestNotifier membuat Notifier dengan aturan ad_without_ocs dan state di direktori sementara
+func newTestNotifier(t *testing.T, stateFile string, channels ...Channel) *Notifier {
+	t.Helper()
+	n, err := NewNotifier(Config{Rules: "ad_without_ocs", MaxItems: 50, StateFile: stateFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Channels = channels
+	return n
+}
+
+func adOnly(name string) parser.FinalComputerRow {
+	return parser.FinalComputerRow{ComputerName: name, ADDomain: "corp", ExistsInAD: true, ADStatus: "enabled"}
+}
+
+// assertMessages memeriksa jumlah pesan dan nama komputer yang disebut/tidak disebut
+func assertMessages(t *testing.T, label string, msgs []string, want int, contains []string, excludes []string) {
+	t.Helper()
+	if len(msgs) != want {
+		t.Fatalf("%s: %d pesan, want %d: %q", label, len(msgs), want, msgs)
+	}
+	all := strings.Join(msgs, "\n")
+	for _, s := range contains {
+		if !strings.Contains(all, s) {
+			t.Errorf("%s: pesan tidak menyebut %s: %q", label, s, all)
+		}
+	}
+	for _, s := range excludes {
+		if strings.Contains(all, s) {
+			t.Errorf("%s: pesan tidak boleh menyebut %s lagi: %q", label, s, all)
+		}
+	}
+}
+
+func TestNotifyWebhookDedupe(t *testing.T) {
+	hook := newFakeWebhook(t)
+	n := newTestNotifier(t, filepath.Join(t.TempDir(), "state.json"), NewWebhookChannel(hook.srv.URL))
+	ctx := context.Background()
+
+	if err := n.Notify(ctx, []parser.FinalComputerRow{adOnly("PC-A")}); err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(t, "siklus 1", hook.take(), 1, []string{"PC-A", "ad_without_ocs"}, nil)
+
+	// Temuan yang sama tidak dikirim ulang
+	if err := n.Notify(ctx, []parser.FinalComputerRow{adOnly("PC-A")}); err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(t, "siklus 2", hook.take(), 0, nil, nil)
+
+	// Hanya temuan baru yang dikirim
+	if err := n.Notify(ctx, []parser.FinalComputerRow{adOnly("PC-A"), adOnly("PC-B")}); err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(t, "siklus 3", hook.take(), 1, []string{"PC-B"}, []string{"PC-A"})
+}
+
+func TestNotifyPerChannelDedupeAndFailure(t *testing.T) {
+	ok := newFakeWebhook(t)
+	flaky := newFakeWebhook(t)
+	flaky.setStatus(http.StatusInternalServerError)
+	n := newTestNotifier(t, filepath.Join(t.TempDir(), "state.json"),
+		NewWebhookChannel(ok.srv.URL), NewWebhookChannel(flaky.srv.URL))
+	ctx := context.Background()
+	rows := []parser.FinalComputerRow{adOnly("PC-A")}
+
+	// Channel yang gagal melaporkan error, channel lain tetap terkirim
+	if err := n.Notify(ctx, rows); err == nil {
+		t.Fatal("Notify harus mengembalikan error channel yang gagal")
+	}
+	assertMessages(t, "ok siklus 1", ok.take(), 1, []string{"PC-A"}, nil)
+	assertMessages(t, "flaky siklus 1", flaky.take(), 0, nil, nil)
+
+	// Temuan tetap pending hanya untuk channel yang gagal
+	flaky.setStatus(http.StatusOK)
+	if err := n.Notify(ctx, rows); err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(t, "ok siklus 2", ok.take(), 0, nil, nil)
+	assertMessages(t, "flaky siklus 2", flaky.take(), 1, []string{"PC-A"}, nil)
+
+	if err := n.Notify(ctx, rows); err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(t, "flaky siklus 3", flaky.take(), 0, nil, nil)
+}
+
+func TestNotifyMaxItemsCarriesOverOmitted(t *testing.T) {
+	hook := newFakeWebhook(t)
+	n := newTestNotifier(t, filepath.Join(t.TempDir(), "state.json"), NewWebhookChannel(hook.srv.URL))
+	n.MaxItems = 2
+	ctx := context.Background()
+	rows := []parser.FinalComputerRow{adOnly("PC-A"), adOnly("PC-B"), adOnly("PC-C")}
+
+	if err := n.Notify(ctx, rows); err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(t, "siklus 1", hook.take(), 1, []string{"PC-A", "PC-B", "dan 1 temuan lainnya"}, []string{"PC-C"})
+
+	// Temuan yang terpotong tidak dianggap terkirim
+	if err := n.Notify(ctx, rows); err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(t, "siklus 2", hook.take(), 1, []string{"PC-C"}, []string{"PC-A", "PC-B", "lainnya"})
+
+	if err := n.Notify(ctx, rows); err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(t, "siklus 3", hook.take(), 0, nil, nil)
+}
+
+func TestNotifyRepeatAfter(t *testing.T) {
+	hook := newFakeWebhook(t)
+	n := newTestNotifier(t, filepath.Join(t.TempDir(), "state.json"), NewWebhookChannel(hook.srv.URL))
+	n.RepeatAfter = time.Hour
+	ctx := context.Background()
+	rows := []parser.FinalComputerRow{adOnly("PC-A")}
+
+	if err := n.Notify(ctx, rows); err != nil {
+		t.Fatal(err)
+	}
+	hook.take()
+
+	// Belum lewat RepeatAfter: tidak dikirim ulang
+	if err := n.Notify(ctx, rows); err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(t, "sebelum RepeatAfter", hook.take(), 0, nil, nil)
+
+	// Mundurkan waktu kirim terakhir melewati RepeatAfter
+	for key := range n.sent {
+		n.sent[key] = time.Now().Add(-2 * time.Hour)
+	}
+	if err := n.Notify(ctx, rows); err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(t, "setelah RepeatAfter", hook.take(), 1, []string{"PC-A"}, nil)
+
+	// Tanpa RepeatAfter temuan lama tidak pernah dikirim ulang
+	n.RepeatAfter = 0
+	for key := range n.sent {
+		n.sent[key] = time.Now().Add(-1000 * time.Hour)
+	}
+	if err := n.Notify(ctx, rows); err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(t, "RepeatAfter 0", hook.take(), 0, nil, nil)
+}
+
+func TestNotifyForgetsResolvedFindings(t *testing.T) {
+	hook := newFakeWebhook(t)
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	n := newTestNotifier(t, stateFile, NewWebhookChannel(hook.srv.URL))
+	ctx := context.Background()
+
+	if err := n.Notify(ctx, []parser.FinalComputerRow{adOnly("PC-A")}); err != nil {
+		t.Fatal(err)
+	}
+	hook.take()
+
+	// PC-A sudah memiliki agent OCS: temuan selesai dan dilupakan
+	resolved := adOnly("PC-A")
+	resolved.ExistsInOCS = true
+	if err := n.Notify(ctx, []parser.FinalComputerRow{resolved}); err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(t, "selesai", hook.take(), 0, nil, nil)
+	if len(n.sent) != 0 {
+		t.Errorf("state masih berisi %v", n.sent)
+	}
+
+	// Muncul kembali: dikirim lagi
+	if err := n.Notify(ctx, []parser.FinalComputerRow{adOnly("PC-A")}); err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(t, "muncul kembali", hook.take(), 1, []string{"PC-A"}, nil)
+
+	// State disimpan ke disk: Notifier baru (mis. setelah restart) tidak mengirim ulang
+	restarted := newTestNotifier(t, stateFile, NewWebhookChannel(hook.srv.URL))
+	if err := restarted.Notify(ctx, []parser.FinalComputerRow{adOnly("PC-A")}); err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(t, "setelah restart", hook.take(), 0, nil, nil)
+}
+
+func TestNotifySMTP(t *testing.T) {
+	mail := newFakeSMTP(t)
+	ch := &SMTPChannel{Host: "127.0.0.1", Port: mail.port, From: "inventory@corp.test", To: []string{"it@corp.test"}}
+	n := newTestNotifier(t, filepath.Join(t.TempDir(), "state.json"), ch)
+	ctx := context.Background()
+	rows := []parser.FinalComputerRow{adOnly("PC-A")}
+
+	// Penerima ditolak: error dan temuan tetap pending
+	mail.mu.Lock()
+	mail.rejectRcpt = true
+	mail.mu.Unlock()
+	if err := n.Notify(ctx, rows); err == nil {
+		t.Fatal("Notify harus gagal saat RCPT ditolak")
+	}
+	assertMessages(t, "ditolak", mail.take(), 0, nil, nil)
+
+	mail.mu.Lock()
+	mail.rejectRcpt = false
+	mail.mu.Unlock()
+	if err := n.Notify(ctx, rows); err != nil {
+		t.Fatal(err)
+	}
+	msgs := mail.take()
+	assertMessages(t, "terkirim", msgs, 1, []string{"PC-A", "To: it@corp.test", "Subject: "}, nil)
+
+	if err := n.Notify(ctx, rows); err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(t, "dedupe", mail.take(), 0, nil, nil)
+}
@@ -0,0 +1,93 @@
+// Package notify mengevaluasi aturan notifikasi terhadap hasil gabungan OCS x AD
+// dan mengirim temuan baru ke webhook (Teams/Slack) maupun email.
+package notify
+
+import (
+	"fmt"
+	"strings"
+
+	"ocs-ad-inventorymanagement/parser"
+)
+
+// Rule adalah satu kondisi drift inventaris yang perlu dilaporkan
+type Rule struct {
+	Name        string
+	Description string
+	Match       func(parser.FinalComputerRow) bool
+}
+
+// builtinRules adalah aturan yang dapat dipilih lewat NOTIFY_RULES
+var builtinRules = []Rule{
+	{
+		Name:        "ad_without_ocs",
+		Description: "Aktif di AD tetapi tidak memiliki agent OCS",
+		Match: func(r parser.FinalComputerRow) bool {
+			return r.ExistsInAD && !r.ExistsInOCS && r.ADStatus != "disabled"
+		},
+	},
+	{
+		Name:        "ocs_without_ad",
+		Description: "Terdaftar di OCS tetapi sudah tidak ada di AD",
+		Match: func(r parser.FinalComputerRow) bool {
+			return r.ExistsInOCS && !r.ExistsInAD
+		},
+	},
+	{
+		Name:        "ad_disabled_ocs_active",
+		Description: "Dinonaktifkan di AD tetapi agent OCS masih melapor (ocs_last_come masih active)",
+		Match: func(r parser.FinalComputerRow) bool {
+			return r.ExistsInAD && r.ADStatus == "disabled" &&
+				r.ExistsInOCS && r.Staleness[parser.StalenessOCSLastCome] == parser.LifecycleActive
+		},
+	},
+	{
+		Name:        "ad_stale",
+		Description: "Aktif di AD tetapi last logon sudah melewati batas stale kebijakan staleness",
+		Match: func(r parser.FinalComputerRow) bool {
+			state := r.Staleness[parser.StalenessADLastLogon]
+			return r.ExistsInAD && r.ADStatus != "disabled" &&
+				(state == parser.LifecycleStale || state == parser.LifecycleAbandoned)
+		},
+	},
+	{
+		Name:        "lifecycle_abandoned",
+		Description: "Semua sumber (AD dan OCS) sudah melewati batas abandoned",
+		Match: func(r parser.FinalComputerRow) bool {
+			return r.LifecycleState == parser.LifecycleAbandoned
+		},
+	},
+}
+
+// ParseRules memilih aturan bawaan berdasarkan daftar nama dipisah koma
+func ParseRules(names string) ([]Rule, error) {
+	var rules []Rule
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		rule, ok := findRule(name)
+		if !ok {
+			return nil, fmt.Errorf("aturan notifikasi %q tidak dikenal (tersedia: %s)", name, ruleNames())
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func findRule(name string) (Rule, bool) {
+	for _, r := range builtinRules {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+func ruleNames() string {
+	names := make([]string, len(builtinRules))
+	for i, r := range builtinRules {
+		names[i] = r.Name
+	}
+	return strings.Join(names, ", ")
+}
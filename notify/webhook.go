@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WebhookChannel mengirim pesan sebagai JSON {"text": ...}, format yang diterima
+// incoming webhook Slack maupun Microsoft Teams
+type WebhookChannel struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookChannel membuat channel webhook dengan timeout 15 detik
+func NewWebhookChannel(u string) *WebhookChannel {
+	return &WebhookChannel{URL: u, Client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// Name mengembalikan identitas channel untuk dedupe, tanpa path (yang biasanya berisi token)
+func (w *WebhookChannel) Name() string {
+	if u, err := url.Parse(w.URL); err == nil && u.Host != "" {
+		return "webhook:" + u.Host + "#" + shortHash(w.URL)
+	}
+	return "webhook:" + shortHash(w.URL)
+}
+
+// Send mengirim pesan ke webhook
+func (w *WebhookChannel) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]string{"text": msg.Subject + "\n\n" + msg.Body})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := w.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("gagal mengirim webhook: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook membalas status %s", res.Status)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"encoding/binary"
 	"fmt"
 	"strconv"
 	"strings"
@@ -11,79 +12,172 @@ import (
 
 // TAMBAHKAN field baru untuk menyimpan informasi OS
 type ComputerReportRow struct {
-	ComputerName     string `json:"computer_name"`
-	OperatingSystem  string `json:"operating_system"`
-	LastLogonTime    string `json:"last_logon_time"`
-	ComputerStatus   string `json:"computer_status"`
-	LastModifiedTime string `json:"ad_last_modified_time"`
+	ComputerName     string    `json:"computer_name"`
+	OperatingSystem  string    `json:"operating_system"`
+	LastLogonTime    time.Time `json:"last_logon_time"` // UTC, zero jika belum pernah login
+	ComputerStatus   string    `json:"computer_status"`
+	LastModifiedTime time.Time `json:"ad_last_modified_time"`
+	LastLogonDC      string    `json:"last_logon_dc,omitempty"` // DC asal nilai lastLogon (mode semua DC)
+	Domain           string    `json:"ad_domain,omitempty"`     // Label sumber AD (multi-domain)
+
+	// Hasil decode userAccountControl
+	UserAccountControl int      `json:"user_account_control"`
+	UACFlags           []string `json:"uac_flags,omitempty"`
+	Role               string   `json:"role,omitempty"` // workstation, server atau dc
+
+	// Atribut tambahan untuk keputusan cleanup
+	DNSHostName           string    `json:"dns_hostname,omitempty"`
+	DistinguishedName     string    `json:"distinguished_name,omitempty"`
+	OU                    string    `json:"ou,omitempty"`
+	Description           string    `json:"description,omitempty"`
+	ManagedBy             string    `json:"managed_by,omitempty"`
+	ObjectSID             string    `json:"object_sid,omitempty"`
+	WhenCreated           time.Time `json:"when_created"`
+	PwdLastSet            time.Time `json:"pwd_last_set"`
+	ServicePrincipalNames []string  `json:"service_principal_names,omitempty"`
 }
 
-func convertLDAPTimestamp(ts string) string {
+// convertLDAPTimestamp mengubah FILETIME AD (interval 100ns sejak 1601-01-01 UTC) menjadi time.Time UTC.
+// Nilai kosong, 0 atau tidak valid menghasilkan zero time.
+func convertLDAPTimestamp(ts string) time.Time {
 	if ts == "" || ts == "0" {
-		return "0"
+		return time.Time{}
 	}
 	i, err := strconv.ParseInt(ts, 10, 64)
-	if err != nil || i == 0 {
-		return "0"
+	if err != nil || i <= 0 {
+		return time.Time{}
 	}
+	return filetimeToTime(i)
+}
 
-	unixTimestamp := (i / 10000000) - 11644473600
-	t := time.Unix(unixTimestamp, 0).UTC()
-	tInWIB := t.Add(7 * time.Hour)
-	return tInWIB.Format("2006-01-02 15:04:05")
+// filetimeToTime mengubah nilai FILETIME menjadi time.Time UTC
+func filetimeToTime(ft int64) time.Time {
+	const epochDiff = 11644473600 // detik antara 1601-01-01 dan 1970-01-01
+	return time.Unix(ft/10000000-epochDiff, (ft%10000000)*100).UTC()
 }
 
-func parseGeneralizedTime(gt string) string {
+// parseGeneralizedTime mengubah GeneralizedTime LDAP (mis. "20240131120000.0Z") menjadi time.Time UTC
+func parseGeneralizedTime(gt string) time.Time {
 	if gt == "" {
-		return ""
+		return time.Time{}
 	}
-	t, err := time.Parse("20060102150405.0Z", gt)
+	// Pecahan detik bersifat opsional; time.Parse menerimanya walau tidak ada di layout
+	t, err := time.Parse("20060102150405Z", gt)
 	if err != nil {
-		return ""
+		return time.Time{}
 	}
-	tInWIB := t.Add(7 * time.Hour)
-	return tInWIB.Format("2006-01-02 15:04:05")
+	return t.UTC()
 }
 
 func ParseComputerReportFromLDAP(entries []*ldap.Entry) ([]ComputerReportRow, error) {
 	var simplifiedList []ComputerReportRow
-	const ufAccountDisable = 2
-
 	for _, entry := range entries {
-		name := entry.GetAttributeValue("name")
-		if name == "" {
-			continue
+		if row, ok := ParseComputerEntry(entry); ok {
+			simplifiedList = append(simplifiedList, row)
 		}
+	}
+	return simplifiedList, nil
+}
 
-		// AMBIL data OS dan format
-		os := entry.GetAttributeValue("operatingSystem")
-		osVersion := entry.GetAttributeValue("operatingSystemVersion")
-		fullOS := os
-		if osVersion != "" {
-			fullOS = fmt.Sprintf("%s (%s)", os, osVersion)
-		}
+// SetLastLogon mengganti waktu login terakhir dengan nilai lastLogon (FILETIME) dari dc.
+// Nilai 0 diabaikan sehingga fallback lastLogonTimestamp tetap dipakai.
+func (r *ComputerReportRow) SetLastLogon(filetime int64, dc string) {
+	if filetime <= 0 {
+		return
+	}
+	r.LastLogonTime = filetimeToTime(filetime)
+	r.LastLogonDC = dc
+}
 
-		uacStr := entry.GetAttributeValue("userAccountControl")
-		uac, _ := strconv.Atoi(uacStr)
-		status := "enabled"
-		if (uac & ufAccountDisable) == ufAccountDisable {
-			status = "disabled"
-		}
+// ParseComputerEntry mengubah satu entry LDAP menjadi ComputerReportRow.
+// Mengembalikan false jika entry tidak memiliki nama.
+func ParseComputerEntry(entry *ldap.Entry) (ComputerReportRow, bool) {
+	name := entry.GetAttributeValue("name")
+	if name == "" {
+		return ComputerReportRow{}, false
+	}
 
-		// Prioritaskan lastLogon (real-time), jika kosong baru pakai lastLogonTimestamp
-		lastLogon := convertLDAPTimestamp(entry.GetAttributeValue("lastLogon"))
-		if lastLogon == "0" {
-			lastLogon = convertLDAPTimestamp(entry.GetAttributeValue("lastLogonTimestamp"))
-		}
+	// AMBIL data OS dan format
+	os := entry.GetAttributeValue("operatingSystem")
+	osVersion := entry.GetAttributeValue("operatingSystemVersion")
+	fullOS := os
+	if osVersion != "" {
+		fullOS = fmt.Sprintf("%s (%s)", os, osVersion)
+	}
 
-		simplifiedList = append(simplifiedList, ComputerReportRow{
-			ComputerName:     name,
-			OperatingSystem:  fullOS, // Simpan informasi OS
-			LastLogonTime:    lastLogon,
-			ComputerStatus:   strings.ToLower(status),
-			LastModifiedTime: parseGeneralizedTime(entry.GetAttributeValue("whenChanged")),
-		})
+	uacStr := entry.GetAttributeValue("userAccountControl")
+	uac, _ := strconv.Atoi(uacStr)
+	status := "enabled"
+	if (uac & UACAccountDisable) == UACAccountDisable {
+		status = "disabled"
 	}
 
-	return simplifiedList, nil
+	// Prioritaskan lastLogon (real-time), jika kosong baru pakai lastLogonTimestamp
+	lastLogon := convertLDAPTimestamp(entry.GetAttributeValue("lastLogon"))
+	if lastLogon.IsZero() {
+		lastLogon = convertLDAPTimestamp(entry.GetAttributeValue("lastLogonTimestamp"))
+	}
+
+	dn := entry.GetAttributeValue("distinguishedName")
+	if dn == "" {
+		dn = entry.DN
+	}
+
+	return ComputerReportRow{
+		ComputerName:     name,
+		OperatingSystem:  fullOS, // Simpan informasi OS
+		LastLogonTime:    lastLogon,
+		ComputerStatus:   strings.ToLower(status),
+		LastModifiedTime: parseGeneralizedTime(entry.GetAttributeValue("whenChanged")),
+
+		UserAccountControl: uac,
+		UACFlags:           DecodeUserAccountControl(uac),
+		Role:               ComputerRole(uac, os),
+
+		DNSHostName:           entry.GetAttributeValue("dNSHostName"),
+		DistinguishedName:     dn,
+		OU:                    parentDN(dn),
+		Description:           entry.GetAttributeValue("description"),
+		ManagedBy:             entry.GetAttributeValue("managedBy"),
+		ObjectSID:             decodeSID(entry.GetRawAttributeValue("objectSid")),
+		WhenCreated:           parseGeneralizedTime(entry.GetAttributeValue("whenCreated")),
+		PwdLastSet:            convertLDAPTimestamp(entry.GetAttributeValue("pwdLastSet")),
+		ServicePrincipalNames: entry.GetAttributeValues("servicePrincipalName"),
+	}, true
+}
+
+// parentDN mengembalikan container (OU) dari sebuah DN, mis.
+// "CN=PC01,OU=Laptop,DC=corp,DC=local" -> "OU=Laptop,DC=corp,DC=local"
+func parentDN(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) < 2 {
+		return ""
+	}
+	parts := make([]string, 0, len(parsed.RDNs)-1)
+	for _, rdn := range parsed.RDNs[1:] {
+		parts = append(parts, rdn.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// decodeSID mengubah objectSid biner menjadi format string "S-1-5-21-..."
+func decodeSID(b []byte) string {
+	if len(b) < 8 {
+		return ""
+	}
+	subCount := int(b[1])
+	if len(b) < 8+4*subCount {
+		return ""
+	}
+	var authority uint64
+	for i := 2; i < 8; i++ {
+		authority = authority<<8 | uint64(b[i])
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "S-%d-%d", b[0], authority)
+	for i := 0; i < subCount; i++ {
+		sub := binary.LittleEndian.Uint32(b[8+4*i:])
+		fmt.Fprintf(&sb, "-%d", sub)
+	}
+	return sb.String()
 }
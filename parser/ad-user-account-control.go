@@ -0,0 +1,89 @@
+package parser
+
+import "strings"
+
+// Flag userAccountControl Active Directory
+// https://learn.microsoft.com/en-us/troubleshoot/windows-server/active-directory/useraccountcontrol-manipulate-account-properties
+const (
+	UACScript                     = 0x0001
+	UACAccountDisable             = 0x0002
+	UACHomedirRequired            = 0x0008
+	UACLockout                    = 0x0010
+	UACPasswdNotReqd              = 0x0020
+	UACPasswdCantChange           = 0x0040
+	UACEncryptedTextPwdAllowed    = 0x0080
+	UACTempDuplicateAccount       = 0x0100
+	UACNormalAccount              = 0x0200
+	UACInterdomainTrustAccount    = 0x0800
+	UACWorkstationTrustAccount    = 0x1000
+	UACServerTrustAccount         = 0x2000
+	UACDontExpirePassword         = 0x10000
+	UACMNSLogonAccount            = 0x20000
+	UACSmartcardRequired          = 0x40000
+	UACTrustedForDelegation       = 0x80000
+	UACNotDelegated               = 0x100000
+	UACUseDESKeyOnly              = 0x200000
+	UACDontReqPreauth             = 0x400000
+	UACPasswordExpired            = 0x800000
+	UACTrustedToAuthForDelegation = 0x1000000
+	UACPartialSecretsAccount      = 0x4000000
+)
+
+// uacFlagNames berurutan sesuai bit agar hasil decode selalu stabil
+var uacFlagNames = []struct {
+	bit  int
+	name string
+}{
+	{UACScript, "SCRIPT"},
+	{UACAccountDisable, "ACCOUNTDISABLE"},
+	{UACHomedirRequired, "HOMEDIR_REQUIRED"},
+	{UACLockout, "LOCKOUT"},
+	{UACPasswdNotReqd, "PASSWD_NOTREQD"},
+	{UACPasswdCantChange, "PASSWD_CANT_CHANGE"},
+	{UACEncryptedTextPwdAllowed, "ENCRYPTED_TEXT_PWD_ALLOWED"},
+	{UACTempDuplicateAccount, "TEMP_DUPLICATE_ACCOUNT"},
+	{UACNormalAccount, "NORMAL_ACCOUNT"},
+	{UACInterdomainTrustAccount, "INTERDOMAIN_TRUST_ACCOUNT"},
+	{UACWorkstationTrustAccount, "WORKSTATION_TRUST_ACCOUNT"},
+	{UACServerTrustAccount, "SERVER_TRUST_ACCOUNT"},
+	{UACDontExpirePassword, "DONT_EXPIRE_PASSWORD"},
+	{UACMNSLogonAccount, "MNS_LOGON_ACCOUNT"},
+	{UACSmartcardRequired, "SMARTCARD_REQUIRED"},
+	{UACTrustedForDelegation, "TRUSTED_FOR_DELEGATION"},
+	{UACNotDelegated, "NOT_DELEGATED"},
+	{UACUseDESKeyOnly, "USE_DES_KEY_ONLY"},
+	{UACDontReqPreauth, "DONT_REQ_PREAUTH"},
+	{UACPasswordExpired, "PASSWORD_EXPIRED"},
+	{UACTrustedToAuthForDelegation, "TRUSTED_TO_AUTH_FOR_DELEGATION"},
+	{UACPartialSecretsAccount, "PARTIAL_SECRETS_ACCOUNT"},
+}
+
+// Role komputer yang diturunkan dari userAccountControl dan operatingSystem
+const (
+	RoleWorkstation      = "workstation"
+	RoleServer           = "server"
+	RoleDomainController = "dc"
+)
+
+// DecodeUserAccountControl mengubah bitmask userAccountControl menjadi daftar nama flag
+func DecodeUserAccountControl(uac int) []string {
+	var flags []string
+	for _, f := range uacFlagNames {
+		if uac&f.bit != 0 {
+			flags = append(flags, f.name)
+		}
+	}
+	return flags
+}
+
+// ComputerRole menentukan role komputer: domain controller (SERVER_TRUST_ACCOUNT atau
+// read-only DC dengan PARTIAL_SECRETS_ACCOUNT), server (OS Windows Server) atau workstation.
+func ComputerRole(uac int, operatingSystem string) string {
+	if uac&UACServerTrustAccount != 0 || uac&UACPartialSecretsAccount != 0 {
+		return RoleDomainController
+	}
+	if strings.Contains(strings.ToLower(operatingSystem), "server") {
+		return RoleServer
+	}
+	return RoleWorkstation
+}
@@ -3,218 +3,404 @@ package parser
 import (
 	"crypto/sha1"
 	"encoding/hex"
+	"encoding/json"
+	"sort"
 	"strings"
 	"time"
 )
 
 type FinalComputerRow struct {
-	ComputerName                string `json:"computer_name"`
-	ExistsInOCS                 bool   `json:"exists_in_ocs"`
-	ExistsInAD                  bool   `json:"exists_in_ad"`
-	OCSStatus                   string `json:"ocs_status"`
-	ADStatus                    string `json:"ad_status"`
-	OCSLastInventory            string `json:"ocs_last_inventory,omitempty"`
-	OCSLastCome                 string `json:"ocs_last_come,omitempty"`
-	ADLastLogonTime             string `json:"ad_last_logon_time,omitempty"`
-	ADLastModifiedTime          string `json:"ad_last_modified_time,omitempty"`
-	ADNotLoginMoreThan30d       *bool  `json:"ad_not_login_more_than_30d,omitempty"`
-	ADNotLoginMoreThan45d       *bool  `json:"ad_not_login_more_than_45d,omitempty"`
-	OCSLastInventoryMoreThan30d *bool  `json:"ocs_last_inventory_more_than_30d,omitempty"`
-	OCSLastComeMoreThan30d      *bool  `json:"ocs_last_come_more_than_30d,omitempty"`
-	OCSInactiveDurationDays     *int   `json:"ocs_inactive_duration_days,omitempty"`
-	ADInactiveDurationDays      *int   `json:"ad_inactive_duration_days,omitempty"`
-	SyncTime                    string `json:"@timestamp"`
+	ComputerName            string     `json:"computer_name"`
+	ADDomain                string     `json:"ad_domain,omitempty"`
+	ExistsInOCS             bool       `json:"exists_in_ocs"`
+	ExistsInAD              bool       `json:"exists_in_ad"`
+	OCSStatus               string     `json:"ocs_status"`
+	ADStatus                string     `json:"ad_status"`
+	OCSLastInventory        *time.Time `json:"ocs_last_inventory"`
+	OCSLastCome             *time.Time `json:"ocs_last_come"`
+	ADLastLogonTime         *time.Time `json:"ad_last_logon_time"`
+	ADLastModifiedTime      *time.Time `json:"ad_last_modified_time"`
+	ADLastLogonDC           string     `json:"ad_last_logon_dc,omitempty"`
+	OCSInactiveDurationDays *int       `json:"ocs_inactive_duration_days,omitempty"`
+	ADInactiveDurationDays  *int       `json:"ad_inactive_duration_days,omitempty"`
+	SyncTime                time.Time  `json:"@timestamp"`
+
+	// Hasil decode userAccountControl AD
+	ADUserAccountControl *int     `json:"ad_user_account_control,omitempty"`
+	ADUACFlags           []string `json:"ad_uac_flags,omitempty"`
+	ADRole               string   `json:"ad_role,omitempty"`
+
+	// Atribut tambahan dari AD
+	ADDNSHostName           string     `json:"ad_dns_hostname,omitempty"`
+	ADDistinguishedName     string     `json:"ad_distinguished_name,omitempty"`
+	ADOU                    string     `json:"ad_ou,omitempty"`
+	ADDescription           string     `json:"ad_description,omitempty"`
+	ADManagedBy             string     `json:"ad_managed_by,omitempty"`
+	ADObjectSID             string     `json:"ad_object_sid,omitempty"`
+	ADWhenCreated           *time.Time `json:"ad_when_created"`
+	ADPwdLastSet            *time.Time `json:"ad_pwd_last_set"`
+	ADServicePrincipalNames []string   `json:"ad_service_principal_names,omitempty"`
+
+	// Detail perangkat dari OCS (hardware, bios, networks)
+	OCSID               int64    `json:"ocs_id,omitempty"`
+	OCSDuplicateCount   int      `json:"ocs_duplicate_count,omitempty"` // Jumlah baris hardware dengan nama sama (>1 jika duplikat)
+	OCSDuplicateIDs     []int64  `json:"ocs_duplicate_ids,omitempty"`   // ID hardware lain yang tidak dipakai untuk penggabungan
+	OCSDeviceID         string   `json:"ocs_device_id,omitempty"`
+	OCSIPAddress        string   `json:"ocs_ip_address,omitempty"`
+	OCSUserID           string   `json:"ocs_user_id,omitempty"`
+	OCSOSName           string   `json:"ocs_os_name,omitempty"`
+	OCSOSVersion        string   `json:"ocs_os_version,omitempty"`
+	OCSWorkgroup        string   `json:"ocs_workgroup,omitempty"`
+	OCSAgentVersion     string   `json:"ocs_agent_version,omitempty"`
+	OCSBIOSSerial       string   `json:"ocs_bios_serial,omitempty"`
+	OCSBIOSManufacturer string   `json:"ocs_bios_manufacturer,omitempty"`
+	OCSBIOSModel        string   `json:"ocs_bios_model,omitempty"`
+	OCSMACAddresses     []string `json:"ocs_mac_addresses,omitempty"`
+	OCSPrimaryMAC       string   `json:"ocs_primary_mac,omitempty"`
+
+	// Perbandingan OS AD dan OCS setelah dinormalkan (lihat NormalizeOSName)
+	ADOperatingSystem string `json:"ad_operating_system,omitempty"`
+	ADOSNormalized    string `json:"ad_os_normalized,omitempty"`
+	OCSOSNormalized   string `json:"ocs_os_normalized,omitempty"`
+	OSMismatch        *bool  `json:"os_mismatch,omitempty"` // nil jika salah satu OS tidak diketahui
+
+	// Penilaian staleness per sumber berdasarkan aturan yang dikonfigurasi
+	Staleness      map[string]string `json:"staleness,omitempty"` // sumber -> active/stale/abandoned
+	StalenessRule  string            `json:"staleness_rule,omitempty"`
+	LifecycleState string            `json:"lifecycle_state,omitempty"` // active, stale atau abandoned
+
+	// Hash isi dokumen untuk mendeteksi perubahan antar siklus
+	ContentHash string `json:"content_hash,omitempty"`
+
+	// AssignedID adalah ID dokumen yang dipertahankan dari siklus sebelumnya (lihat DocumentID)
+	AssignedID string `json:"-"`
 }
 
-// HashComputerName membuat hash dari nama komputer untuk deduplikasi.
-func HashComputerName(name string) string {
-	// Normalisasi: hapus semua spasi, ubah ke huruf kecil, hilangkan karakter non-alfanumerik
-	norm := strings.ToLower(name)
-	norm = strings.ReplaceAll(norm, " ", "")
-	norm = strings.TrimSpace(norm)
-	// Hilangkan karakter non-alfanumerik
+// setOCSAttributes menyalin detail perangkat OCS ke baris gabungan
+func (r *FinalComputerRow) setOCSAttributes(ocs OCSComputerRow) {
+	r.OCSID = ocs.ID
+	r.OCSDeviceID = ocs.DeviceID
+	r.OCSIPAddress = ocs.IPAddress
+	r.OCSUserID = ocs.UserID
+	r.OCSOSName = ocs.OSName
+	r.OCSOSVersion = ocs.OSVersion
+	r.OCSWorkgroup = ocs.Workgroup
+	r.OCSAgentVersion = ocs.AgentVersion
+	r.OCSBIOSSerial = ocs.BIOSSerial
+	r.OCSBIOSManufacturer = ocs.BIOSManufacturer
+	r.OCSBIOSModel = ocs.BIOSModel
+	r.OCSMACAddresses = ocs.MACAddresses
+	if len(ocs.MACAddresses) > 0 {
+		r.OCSPrimaryMAC = ocs.MACAddresses[0]
+	}
+	r.OCSOSNormalized = NormalizeOSName(ocs.OSName)
+	r.compareOS()
+}
+
+// compareOS mengisi OSMismatch jika OS dari kedua sumber diketahui
+func (r *FinalComputerRow) compareOS() {
+	if r.ADOSNormalized == "" || r.OCSOSNormalized == "" {
+		r.OSMismatch = nil
+		return
+	}
+	b := r.ADOSNormalized != r.OCSOSNormalized
+	r.OSMismatch = &b
+}
+
+// setADAttributes menyalin atribut tambahan AD ke baris gabungan
+func (r *FinalComputerRow) setADAttributes(ad ComputerReportRow) {
+	r.ADOperatingSystem = ad.OperatingSystem
+	r.ADOSNormalized = NormalizeOSName(ad.OperatingSystem)
+	r.compareOS()
+	r.ADLastLogonDC = ad.LastLogonDC
+	uac := ad.UserAccountControl
+	r.ADUserAccountControl = &uac
+	r.ADUACFlags = ad.UACFlags
+	r.ADRole = ad.Role
+	r.ADDNSHostName = ad.DNSHostName
+	r.ADDistinguishedName = ad.DistinguishedName
+	r.ADOU = ad.OU
+	r.ADDescription = ad.Description
+	r.ADManagedBy = ad.ManagedBy
+	r.ADObjectSID = ad.ObjectSID
+	r.ADWhenCreated = displayTime(ad.WhenCreated)
+	r.ADPwdLastSet = displayTime(ad.PwdLastSet)
+	r.ADServicePrincipalNames = ad.ServicePrincipalNames
+}
+
+// DocumentID mengembalikan ID dokumen Elasticsearch untuk baris ini.
+// Komputer dari sumber AD berlabel domain memakai "<nama>@<domain>" agar nama yang sama
+// di domain berbeda tidak saling menimpa. Jika AssignedID diisi, ID tersebut yang dipakai
+// agar ID tidak berganti saat komputer baru cocok atau tidak lagi cocok dengan AD.
+func (r FinalComputerRow) DocumentID() string {
+	if r.AssignedID != "" {
+		return r.AssignedID
+	}
+	if r.ADDomain == "" {
+		return r.ComputerName
+	}
+	return r.ComputerName + "@" + r.ADDomain
+}
+
+// ComputeContentHash membuat hash isi baris, tanpa @timestamp (bisa berisi waktu sekarang)
+// dan tanpa ContentHash itu sendiri. Baris dengan hash sama tidak perlu di-index ulang.
+func (r FinalComputerRow) ComputeContentHash() string {
+	r.SyncTime = time.Time{}
+	r.ContentHash = ""
+	b, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	h := sha1.Sum(b)
+	return hex.EncodeToString(h[:])
+}
+
+// HashComputerKey membuat hash dari domain dan nama komputer.
+// Untuk domain kosong hasilnya sama dengan HashComputerName.
+func HashComputerKey(domain, name string) string {
+	if domain == "" {
+		return HashComputerName(name)
+	}
+	return strings.ToLower(domain) + ":" + HashComputerName(name)
+}
+
+// NormalizeComputerName menormalkan nama komputer untuk pencocokan:
+// huruf kecil dan hanya karakter alfanumerik (spasi, tanda hubung dan titik dibuang).
+func NormalizeComputerName(name string) string {
 	var filtered []rune
-	for _, r := range norm {
+	for _, r := range strings.ToLower(name) {
 		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
 			filtered = append(filtered, r)
 		}
 	}
-	norm = string(filtered)
+	return string(filtered)
+}
+
+// HashComputerName membuat hash dari nama komputer untuk deduplikasi.
+func HashComputerName(name string) string {
 	h := sha1.New()
-	h.Write([]byte(norm))
+	h.Write([]byte(NormalizeComputerName(name)))
 	return hex.EncodeToString(h.Sum(nil))
 }
 
-// CombineOCSAndAD melakukan deduplikasi dan penggabungan data dari OCS dan AD.
-func CombineOCSAndAD(ocsList []OCSComputerRow, adList []ComputerReportRow) []FinalComputerRow {
-	cache := make(map[string]string) // hash -> computer_name
-	result := make(map[string]*FinalComputerRow)
+// syncTimestamp mengisi @timestamp (SyncTime).
+// Memilih timestamp prioritas (OCS > AD) dalam zona tampilan;
+// jika keduanya kosong dipakai waktu sekarang.
+func syncTimestamp(ocsLastInventory, adLastLogon time.Time) time.Time {
+	// Prioritas 1: OCSLastInventory
+	if !ocsLastInventory.IsZero() {
+		return ocsLastInventory.In(displayLocation)
+	}
+	// Prioritas 2: ADLastLogon
+	if !adLastLogon.IsZero() {
+		return adLastLogon.In(displayLocation)
+	}
+	return time.Now().In(displayLocation)
+}
 
-	// Helper untuk mem-parsing berbagai format waktu, mengembalikan time.Time dan status keberhasilan.
-	parseTime := func(t string) (time.Time, bool) {
-		if t == "" {
-			return time.Time{}, false
-		}
-		// Daftar layout format waktu yang mungkin diterima
-		layouts := []string{"2006-01-02 15:04:05", time.RFC3339, "2006-01-02T15:04:05Z"}
-		for _, layout := range layouts {
-			parsed, err := time.Parse(layout, t)
-			if err == nil {
-				return parsed, true
-			}
-		}
-		return time.Time{}, false
+// Combiner menggabungkan data OCS dan AD secara bertahap, sehingga data OCS bisa
+// dialirkan per halaman tanpa ditampung seluruhnya. Semua data OCS harus ditambahkan
+// sebelum data AD.
+type Combiner struct {
+	cache     map[string]string         // hash -> key di result
+	claimed   map[string]struct{}       // hash OCS yang sudah dipasangkan dengan komputer AD
+	ocsChosen map[string]OCSComputerRow // hash -> baris OCS terbaru yang dipakai
+	ocsIDs    map[string][]int64        // hash -> semua hardware.ID dengan nama tersebut
+	result    map[string]*FinalComputerRow
+	now       time.Time
+}
+
+// OCSDuplicate adalah sekelompok baris hardware OCS dengan nama komputer yang sama
+type OCSDuplicate struct {
+	ComputerName   string    `json:"computer_name"`
+	Count          int       `json:"count"`
+	IDs            []int64   `json:"ids"`
+	ChosenID       int64     `json:"chosen_id"` // Baris dengan LASTCOME terbaru, dipakai untuk penggabungan
+	NewestLastCome time.Time `json:"newest_last_come"`
+}
+
+// NewCombiner membuat Combiner kosong
+func NewCombiner() *Combiner {
+	return &Combiner{
+		cache:     make(map[string]string),
+		claimed:   make(map[string]struct{}),
+		ocsChosen: make(map[string]OCSComputerRow),
+		ocsIDs:    make(map[string][]int64),
+		result:    make(map[string]*FinalComputerRow),
+		now:       time.Now(),
 	}
+}
 
-	// Helper baru untuk mengisi @timestamp (SyncTime) dengan zona waktu UTC-7.
-	// Fungsi ini memilih timestamp prioritas (OCS > AD), memformatnya ke RFC3339,
-	// dan memberikan nilai default jika keduanya tidak valid.
-	getSyncTimestamp := func(ocsLastInventory, adLastLogon string) string {
-		shift := -7 * time.Hour
-		isZeroOrDash := func(s string) bool {
-			return s == "0" || s == "-"
-		}
-		// Prioritas 1: Coba parse OCSLastInventory
-		if !isZeroOrDash(ocsLastInventory) {
-			if t, ok := parseTime(ocsLastInventory); ok {
-				return t.Add(shift).UTC().Format(time.RFC3339)
-			}
-		}
-		// Prioritas 2: Coba parse ADLastLogon
-		if !isZeroOrDash(adLastLogon) {
-			if t, ok := parseTime(adLastLogon); ok {
-				return t.Add(shift).UTC().Format(time.RFC3339)
-			}
-		}
-		// Jika field bernilai 0 atau -, gunakan waktu UTC+0 (tanpa shift)
-		return time.Now().UTC().Format(time.RFC3339)
+// newerOCS menentukan apakah a lebih baru dari b: LASTCOME terbaru, lalu LASTDATE terbaru,
+// lalu ID terbesar, sehingga pilihan di antara duplikat selalu sama di setiap siklus
+func newerOCS(a, b OCSComputerRow) bool {
+	if !a.OCSLastCome.Equal(b.OCSLastCome) {
+		return a.OCSLastCome.After(b.OCSLastCome)
+	}
+	if !a.OCSLastInventory.Equal(b.OCSLastInventory) {
+		return a.OCSLastInventory.After(b.OCSLastInventory)
 	}
+	return a.ID > b.ID
+}
 
-	// Helper untuk pengecekan string "0" atau "-" dan waktu sekarang
-	isZeroOrDash := func(s string) bool {
-		return s == "0" || s == "-"
+// AddOCS menambahkan satu komputer dari OCS.
+// Jika nama yang sama sudah ada (mis. komputer di-reimage dengan DEVICEID baru),
+// hanya baris terbaru yang dipakai dan baris lain dicatat sebagai duplikat.
+func (c *Combiner) AddOCS(ocs OCSComputerRow) {
+	key := HashComputerName(ocs.ComputerName)
+	c.ocsIDs[key] = append(c.ocsIDs[key], ocs.ID)
+	if chosen, ok := c.ocsChosen[key]; ok && !newerOCS(ocs, chosen) {
+		return
 	}
-	now := time.Now().Local()
+	c.ocsChosen[key] = ocs
+	c.cache[key] = "ocs:" + key
+	// fmt.Printf("[DEBUG][OCS] ComputerName: '%s' | Hash: '%s'\n", ocs.ComputerName, key)
 
-	// Proses data dari OCS
-	for _, ocs := range ocsList {
-		key := HashComputerName(ocs.ComputerName)
-		cache[key] = ocs.ComputerName
-		// fmt.Printf("[DEBUG][OCS] ComputerName: '%s' | Hash: '%s'\n", ocs.ComputerName, key)
-
-		// Logic untuk ocs_last_inventory_more_than_30d dan ocs_last_come_more_than_30d
-		var ocsLastInventoryMoreThan30d *bool
-		var ocsLastComeMoreThan30d *bool
-		var ocsInactiveDurationDays *int
-		if !isZeroOrDash(ocs.OCSLastInventory) {
-			if t, ok := parseTime(ocs.OCSLastInventory); ok {
-				b := now.Sub(t).Hours() > 24*30
-				ocsLastInventoryMoreThan30d = &b
-			}
-		} else {
-			b := true
-			ocsLastInventoryMoreThan30d = &b
-		}
-		if !isZeroOrDash(ocs.OCSLastCome) {
-			if t, ok := parseTime(ocs.OCSLastCome); ok {
-				b := now.Sub(t).Hours() > 24*30
-				ocsLastComeMoreThan30d = &b
-				days := int(now.Sub(t).Hours() / 24)
-				ocsInactiveDurationDays = &days
-			}
-		} else {
-			b := true
-			ocsLastComeMoreThan30d = &b
-		}
+	// Batas stale/abandoned dinilai oleh kebijakan staleness di Rows
+	var ocsInactiveDurationDays *int
+	if t := ocs.OCSLastCome; !t.IsZero() {
+		days := int(c.now.Sub(t).Hours() / 24)
+		ocsInactiveDurationDays = &days
+	}
 
-		result[ocs.ComputerName] = &FinalComputerRow{
-			ComputerName: ocs.ComputerName,
-			ExistsInOCS:  true,
-			ExistsInAD:   false,
-			OCSStatus:    ocs.OCSStatus,
-			ADStatus:     "",
-			// Aturan 2: Biarkan format string original
-			OCSLastInventory:            ocs.OCSLastInventory,
-			OCSLastCome:                 ocs.OCSLastCome,
-			ADLastLogonTime:             "",
-			ADNotLoginMoreThan30d:       nil,
-			ADNotLoginMoreThan45d:       nil,
-			OCSLastInventoryMoreThan30d: ocsLastInventoryMoreThan30d,
-			OCSLastComeMoreThan30d:      ocsLastComeMoreThan30d,
-			OCSInactiveDurationDays:     ocsInactiveDurationDays,
-			ADInactiveDurationDays:      nil,
-			// Aturan 1 & 3: Buat @timestamp dalam format RFC3339 dari data OCS
-			SyncTime: getSyncTimestamp(ocs.OCSLastInventory, ""),
-		}
+	row := &FinalComputerRow{
+		ComputerName: ocs.ComputerName,
+		ExistsInOCS:  true,
+		ExistsInAD:   false,
+		OCSStatus:    ocs.OCSStatus,
+		ADStatus:     "",
+		// Aturan 2: Tanggal bertipe waktu (RFC3339 dengan offset), null jika kosong
+		OCSLastInventory:        displayTime(ocs.OCSLastInventory),
+		OCSLastCome:             displayTime(ocs.OCSLastCome),
+		ADLastLogonTime:         nil,
+		OCSInactiveDurationDays: ocsInactiveDurationDays,
+		ADInactiveDurationDays:  nil,
+		// Aturan 1 & 3: Buat @timestamp dalam format RFC3339 dari data OCS
+		SyncTime: syncTimestamp(ocs.OCSLastInventory, time.Time{}),
 	}
+	row.setOCSAttributes(ocs)
+	c.result["ocs:"+key] = row
+}
 
-	// Proses data dari AD dan gabungkan dengan data OCS yang ada
-	// now dan isZeroOrDash sudah didefinisikan di atas
-	for _, ad := range adList {
-		key := HashComputerName(ad.ComputerName)
-		// fmt.Printf("[DEBUG][AD] ComputerName: '%s' | Hash: '%s'\n", ad.ComputerName, key)
-
-		// Hitung field login > 30/45 hari
-		var moreThan30d *bool
-		var moreThan45d *bool
-		var adInactiveDurationDays *int
-		if !isZeroOrDash(ad.LastLogonTime) {
-			if t, ok := parseTime(ad.LastLogonTime); ok {
-				b30 := now.Sub(t).Hours() > 24*30
-				b45 := now.Sub(t).Hours() > 24*45
-				moreThan30d = &b30
-				moreThan45d = &b45
-				days := int(now.Sub(t).Hours() / 24)
-				adInactiveDurationDays = &days
-			}
-		} else {
-			b := true
-			moreThan30d = &b
-			moreThan45d = &b
+// AddAD menambahkan satu komputer dari AD dan menggabungkannya dengan data OCS yang cocok
+func (c *Combiner) AddAD(ad ComputerReportRow) {
+	key := HashComputerName(ad.ComputerName)
+	// fmt.Printf("[DEBUG][AD] ComputerName: '%s' | Hash: '%s'\n", ad.ComputerName, key)
+
+	// Batas stale/abandoned dinilai oleh kebijakan staleness di Rows
+	var adInactiveDurationDays *int
+	if t := ad.LastLogonTime; !t.IsZero() {
+		days := int(c.now.Sub(t).Hours() / 24)
+		adInactiveDurationDays = &days
+	}
+
+	// OCS tidak menyimpan domain, sehingga satu data OCS hanya dipasangkan dengan
+	// komputer AD pertama yang cocok. Nama yang sama dari domain lain menjadi baris terpisah.
+	cname, ok := c.cache[key]
+	if _, taken := c.claimed[key]; taken {
+		ok = false
+	}
+	if ok {
+		// Komputer sudah ada di OCS, update data AD
+		c.claimed[key] = struct{}{}
+		row := c.result[cname]
+		row.ADDomain = ad.Domain
+		row.ExistsInAD = true
+		row.ADStatus = ad.ComputerStatus
+		// Aturan 2: Tanggal bertipe waktu (RFC3339 dengan offset), null jika kosong
+		row.ADLastLogonTime = displayTime(ad.LastLogonTime)
+		row.ADLastModifiedTime = displayTime(ad.LastModifiedTime)
+		row.ADInactiveDurationDays = adInactiveDurationDays
+		// Aturan 1 & 3: Update @timestamp dengan mempertimbangkan data OCS dan AD
+		row.SyncTime = syncTimestamp(c.ocsChosen[key].OCSLastInventory, ad.LastLogonTime)
+		row.setADAttributes(ad)
+	} else {
+		// Komputer hanya ada di AD
+		row := &FinalComputerRow{
+			ComputerName:     ad.ComputerName,
+			ADDomain:         ad.Domain,
+			ExistsInOCS:      false,
+			ExistsInAD:       true,
+			OCSStatus:        "",
+			ADStatus:         ad.ComputerStatus,
+			OCSLastInventory: nil,
+			OCSLastCome:      nil,
+			// Aturan 2: Tanggal bertipe waktu (RFC3339 dengan offset), null jika kosong
+			ADLastLogonTime:         displayTime(ad.LastLogonTime),
+			ADLastModifiedTime:      displayTime(ad.LastModifiedTime),
+			OCSInactiveDurationDays: nil,
+			ADInactiveDurationDays:  adInactiveDurationDays,
+			// Aturan 1 & 3: Buat @timestamp dalam format RFC3339 dari data AD
+			SyncTime: syncTimestamp(time.Time{}, ad.LastLogonTime),
 		}
+		row.setADAttributes(ad)
+		c.result["ad:"+HashComputerKey(ad.Domain, ad.ComputerName)] = row
+	}
+}
 
-		if cname, ok := cache[key]; ok {
-			// Komputer sudah ada di OCS, update data AD
-			row := result[cname]
-			row.ExistsInAD = true
-			row.ADStatus = ad.ComputerStatus
-			// Aturan 2: Biarkan format string original
-			row.ADLastLogonTime = ad.LastLogonTime
-			row.ADLastModifiedTime = ad.LastModifiedTime
-			row.ADNotLoginMoreThan30d = moreThan30d
-			row.ADNotLoginMoreThan45d = moreThan45d
-			row.ADInactiveDurationDays = adInactiveDurationDays
-			// Aturan 1 & 3: Update @timestamp dengan mempertimbangkan data OCS dan AD
-			row.SyncTime = getSyncTimestamp(row.OCSLastInventory, ad.LastLogonTime)
-		} else {
-			// Komputer hanya ada di AD
-			result[ad.ComputerName] = &FinalComputerRow{
-				ComputerName:     ad.ComputerName,
-				ExistsInOCS:      false,
-				ExistsInAD:       true,
-				OCSStatus:        "",
-				ADStatus:         ad.ComputerStatus,
-				OCSLastInventory: "",
-				OCSLastCome:      "",
-				// Aturan 2: Biarkan format string original
-				ADLastLogonTime:             ad.LastLogonTime,
-				ADLastModifiedTime:          ad.LastModifiedTime,
-				ADNotLoginMoreThan30d:       moreThan30d,
-				ADNotLoginMoreThan45d:       moreThan45d,
-				OCSLastInventoryMoreThan30d: nil,
-				OCSLastComeMoreThan30d:      nil,
-				OCSInactiveDurationDays:     nil,
-				ADInactiveDurationDays:      adInactiveDurationDays,
-				// Aturan 1 & 3: Buat @timestamp dalam format RFC3339 dari data AD
-				SyncTime: getSyncTimestamp("", ad.LastLogonTime),
-			}
+// Rows mengembalikan hasil gabungan beserta penilaian staleness
+func (c *Combiner) Rows() []FinalComputerRow {
+	// Tandai baris yang memiliki duplikat di OCS
+	for key, ids := range c.ocsIDs {
+		if len(ids) < 2 {
+			continue
 		}
+		row := c.result[c.cache[key]]
+		row.OCSDuplicateCount = len(ids)
+		row.OCSDuplicateIDs = otherIDs(ids, c.ocsChosen[key].ID)
 	}
 
-	// Konversi map hasil ke slice untuk dikembalikan
 	var finalList []FinalComputerRow
-	for _, v := range result {
+	for _, v := range c.result {
+		v.applyStaleness(stalenessPolicy, c.now)
 		finalList = append(finalList, *v)
 	}
 	return finalList
 }
+
+// Duplicates mengembalikan semua nama komputer yang memiliki lebih dari satu baris hardware di OCS,
+// terurut berdasarkan nama
+func (c *Combiner) Duplicates() []OCSDuplicate {
+	var dups []OCSDuplicate
+	for key, ids := range c.ocsIDs {
+		if len(ids) < 2 {
+			continue
+		}
+		chosen := c.ocsChosen[key]
+		sorted := append([]int64(nil), ids...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		dups = append(dups, OCSDuplicate{
+			ComputerName:   chosen.ComputerName,
+			Count:          len(ids),
+			IDs:            sorted,
+			ChosenID:       chosen.ID,
+			NewestLastCome: chosen.OCSLastCome,
+		})
+	}
+	sort.Slice(dups, func(i, j int) bool { return dups[i].ComputerName < dups[j].ComputerName })
+	return dups
+}
+
+// otherIDs mengembalikan ids tanpa chosen, terurut menaik
+func otherIDs(ids []int64, chosen int64) []int64 {
+	var out []int64
+	for _, id := range ids {
+		if id != chosen {
+			out = append(out, id)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// CombineOCSAndAD melakukan deduplikasi dan penggabungan data dari OCS dan AD.
+func CombineOCSAndAD(ocsList []OCSComputerRow, adList []ComputerReportRow) []FinalComputerRow {
+	c := NewCombiner()
+	for _, ocs := range ocsList {
+		c.AddOCS(ocs)
+	}
+	for _, ad := range adList {
+		c.AddAD(ad)
+	}
+	return c.Rows()
+}
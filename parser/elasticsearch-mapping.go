@@ -0,0 +1,63 @@
+package parser
+
+// ComputerIndexProperties adalah mapping eksplisit (nama field -> tipe Elasticsearch) untuk FinalComputerRow.
+// Harus diperbarui setiap kali field JSON di FinalComputerRow ditambah atau diubah.
+var ComputerIndexProperties = map[string]string{
+	"computer_name":              "keyword",
+	"ad_domain":                  "keyword",
+	"exists_in_ocs":              "boolean",
+	"exists_in_ad":               "boolean",
+	"ocs_status":                 "keyword",
+	"ad_status":                  "keyword",
+	"ocs_last_inventory":         "date",
+	"ocs_last_come":              "date",
+	"ad_last_logon_time":         "date",
+	"ad_last_modified_time":      "date",
+	"ad_last_logon_dc":           "keyword",
+	"ocs_inactive_duration_days": "integer",
+	"ad_inactive_duration_days":  "integer",
+	"@timestamp":                 "date",
+
+	"ad_user_account_control": "integer",
+	"ad_uac_flags":            "keyword",
+	"ad_role":                 "keyword",
+
+	"ad_dns_hostname":            "keyword",
+	"ad_distinguished_name":      "keyword",
+	"ad_ou":                      "keyword",
+	"ad_description":             "text",
+	"ad_managed_by":              "keyword",
+	"ad_object_sid":              "keyword",
+	"ad_when_created":            "date",
+	"ad_pwd_last_set":            "date",
+	"ad_service_principal_names": "keyword",
+
+	"ocs_id":                "long",
+	"ocs_duplicate_count":   "integer",
+	"ocs_duplicate_ids":     "long",
+	"ocs_device_id":         "keyword",
+	"ocs_ip_address":        "keyword",
+	"ocs_user_id":           "keyword",
+	"ocs_os_name":           "keyword",
+	"ocs_os_version":        "keyword",
+	"ocs_workgroup":         "keyword",
+	"ocs_agent_version":     "keyword",
+	"ocs_bios_serial":       "keyword",
+	"ocs_bios_manufacturer": "keyword",
+	"ocs_bios_model":        "keyword",
+	"ocs_mac_addresses":     "keyword",
+	"ocs_primary_mac":       "keyword",
+
+	"ad_operating_system": "keyword",
+	"ad_os_normalized":    "keyword",
+	"ocs_os_normalized":   "keyword",
+	"os_mismatch":         "boolean",
+
+	"staleness.ad_last_logon":      "keyword",
+	"staleness.ocs_last_come":      "keyword",
+	"staleness.ocs_last_inventory": "keyword",
+	"staleness_rule":               "keyword",
+	"lifecycle_state":              "keyword",
+
+	"content_hash": "keyword",
+}
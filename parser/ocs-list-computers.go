@@ -1,58 +1,168 @@
 package parser
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type OCSComputerRow struct {
-	ComputerName     string `json:"computer_name"`
-	OCSStatus        string `json:"ocs_status"`
-	OCSLastCome      string `json:"ocs_last_come"`
-	OCSLastInventory string `json:"ocs_last_inventory"`
+	ID               int64     `json:"ocs_id"` // hardware.ID
+	ComputerName     string    `json:"computer_name"`
+	OCSStatus        string    `json:"ocs_status"`
+	OCSLastCome      time.Time `json:"ocs_last_come"`      // UTC, zero jika kosong
+	OCSLastInventory time.Time `json:"ocs_last_inventory"` // UTC, zero jika kosong
+
+	// Detail perangkat dari tabel hardware, bios dan networks
+	DeviceID         string   `json:"ocs_device_id,omitempty"`
+	IPAddress        string   `json:"ocs_ip_address,omitempty"`
+	UserID           string   `json:"ocs_user_id,omitempty"`
+	OSName           string   `json:"ocs_os_name,omitempty"`
+	OSVersion        string   `json:"ocs_os_version,omitempty"`
+	Workgroup        string   `json:"ocs_workgroup,omitempty"`
+	AgentVersion     string   `json:"ocs_agent_version,omitempty"`
+	BIOSSerial       string   `json:"ocs_bios_serial,omitempty"`
+	BIOSManufacturer string   `json:"ocs_bios_manufacturer,omitempty"`
+	BIOSModel        string   `json:"ocs_bios_model,omitempty"`
+	MACAddresses     []string `json:"ocs_mac_addresses,omitempty"` // MAC utama (interface dengan IPADDR) di urutan pertama
 }
 
 type Hardware struct {
+	ID       int64      `gorm:"column:ID"`
 	Name     string     `gorm:"column:NAME"`
 	Archive  *int       `gorm:"column:ARCHIVE"`
 	LastDate *time.Time `gorm:"column:LASTDATE"`
 	LastCome *time.Time `gorm:"column:LASTCOME"`
+
+	DeviceID  string `gorm:"column:DEVICEID"`
+	IPAddr    string `gorm:"column:IPADDR"`
+	UserID    string `gorm:"column:USERID"`
+	OSName    string `gorm:"column:OSNAME"`
+	OSVersion string `gorm:"column:OSVERSION"`
+	Workgroup string `gorm:"column:WORKGROUP"`
+	UserAgent string `gorm:"column:USERAGENT"`
+
+	// Kolom hasil join bios dan networks
+	BIOSSerial       *string `gorm:"column:BIOS_SSN;->"`
+	BIOSManufacturer *string `gorm:"column:BIOS_SMANUFACTURER;->"`
+	BIOSModel        *string `gorm:"column:BIOS_SMODEL;->"`
+	MACAddresses     *string `gorm:"column:MACADDRS;->"`
 }
 
 func (Hardware) TableName() string {
 	return "hardware"
 }
 
-func ListOCSComputers(db *gorm.DB, limit int) ([]OCSComputerRow, error) {
-	var hardwares []Hardware
-	q := db
-	if limit > 0 {
-		q = q.Limit(limit)
-	}
-	if err := q.Find(&hardwares).Error; err != nil {
-		return nil, err
-	}
+// hardwareColumns adalah kolom hardware beserta detail bios dan networks dalam satu query.
+// MAC diurutkan agar interface yang IP-nya sama dengan hardware.IPADDR berada di depan.
+const hardwareColumns = `h.ID, h.NAME, h.ARCHIVE, h.LASTDATE, h.LASTCOME,
+	h.DEVICEID, h.IPADDR, h.USERID, h.OSNAME, h.OSVERSION, h.WORKGROUP, h.USERAGENT,
+	MAX(b.SSN) AS BIOS_SSN, MAX(b.SMANUFACTURER) AS BIOS_SMANUFACTURER, MAX(b.SMODEL) AS BIOS_SMODEL,
+	GROUP_CONCAT(DISTINCT n.MACADDR ORDER BY n.IPADDRESS = h.IPADDR DESC, n.MACADDR SEPARATOR ',') AS MACADDRS`
+
+// hardwareQuery membangun join hardware x bios x networks, satu baris per komputer
+func hardwareQuery(db *gorm.DB) *gorm.DB {
+	return db.Table("hardware h").
+		Select(hardwareColumns).
+		Joins("LEFT JOIN bios b ON b.HARDWARE_ID = h.ID").
+		Joins("LEFT JOIN networks n ON n.HARDWARE_ID = h.ID AND n.MACADDR <> '' AND n.MACADDR <> '00:00:00:00:00:00'").
+		Group("h.ID")
+}
+
+// DefaultOCSPageSize adalah jumlah baris hardware per halaman jika pageSize <= 0
+const DefaultOCSPageSize = 1000
+
+// ListOCSComputers membaca seluruh komputer OCS per halaman berukuran pageSize
+func ListOCSComputers(db *gorm.DB, pageSize int) ([]OCSComputerRow, error) {
 	var result []OCSComputerRow
-	for _, hw := range hardwares {
-		status := "enabled"
-		if hw.Archive != nil {
-			status = "disabled"
+	err := ListOCSComputersFunc(db, pageSize, func(row OCSComputerRow) error {
+		result = append(result, row)
+		return nil
+	})
+	return result, err
+}
+
+// ListOCSComputersFunc membaca tabel hardware dengan keyset pagination berdasarkan ID
+// (WHERE h.ID > id_terakhir ORDER BY h.ID LIMIT pageSize) dan memanggil fn untuk setiap komputer,
+// sehingga memori tetap datar berapa pun jumlah baris. Error dari fn menghentikan pembacaan.
+func ListOCSComputersFunc(db *gorm.DB, pageSize int, fn func(OCSComputerRow) error) error {
+	if pageSize <= 0 {
+		pageSize = DefaultOCSPageSize
+	}
+	var lastID int64
+	for {
+		var hardwares []Hardware
+		err := hardwareQuery(db).
+			Where("h.ID > ?", lastID).
+			Order("h.ID").
+			Limit(pageSize).
+			Scan(&hardwares).Error
+		if err != nil {
+			return err
 		}
-		lastCome := ""
-		lastInventory := ""
-		if hw.LastCome != nil {
-			lastCome = hw.LastCome.Format("2006-01-02 15:04:05")
+		for _, hw := range hardwares {
+			if err := fn(hw.toRow()); err != nil {
+				return err
+			}
 		}
-		if hw.LastDate != nil {
-			lastInventory = hw.LastDate.Format("2006-01-02 15:04:05")
+		if len(hardwares) < pageSize {
+			return nil
 		}
-		result = append(result, OCSComputerRow{
-			ComputerName:     hw.Name,
-			OCSStatus:        status,
-			OCSLastCome:      lastCome,
-			OCSLastInventory: lastInventory,
-		})
-	}
-	return result, nil
+		lastID = hardwares[len(hardwares)-1].ID
+	}
+}
+
+// toRow mengubah satu baris hardware menjadi OCSComputerRow
+func (hw Hardware) toRow() OCSComputerRow {
+	status := "enabled"
+	if hw.Archive != nil {
+		status = "disabled"
+	}
+	// Driver MySQL sudah menerapkan zona OCS_DB_TZ, di sini cukup dinormalisasi ke UTC
+	var lastCome, lastInventory time.Time
+	if hw.LastCome != nil {
+		lastCome = hw.LastCome.UTC()
+	}
+	if hw.LastDate != nil {
+		lastInventory = hw.LastDate.UTC()
+	}
+	return OCSComputerRow{
+		ID:               hw.ID,
+		ComputerName:     hw.Name,
+		OCSStatus:        status,
+		OCSLastCome:      lastCome,
+		OCSLastInventory: lastInventory,
+
+		DeviceID:         hw.DeviceID,
+		IPAddress:        hw.IPAddr,
+		UserID:           hw.UserID,
+		OSName:           hw.OSName,
+		OSVersion:        hw.OSVersion,
+		Workgroup:        hw.Workgroup,
+		AgentVersion:     hw.UserAgent,
+		BIOSSerial:       deref(hw.BIOSSerial),
+		BIOSManufacturer: deref(hw.BIOSManufacturer),
+		BIOSModel:        deref(hw.BIOSModel),
+		MACAddresses:     splitNonEmpty(deref(hw.MACAddresses), ","),
+	}
+}
+
+// deref mengembalikan isi pointer string, atau "" untuk nil (kolom hasil LEFT JOIN)
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return strings.TrimSpace(*s)
+}
+
+// splitNonEmpty memecah s dengan sep dan membuang bagian kosong
+func splitNonEmpty(s, sep string) []string {
+	var out []string
+	for _, part := range strings.Split(s, sep) {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
 }
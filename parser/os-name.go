@@ -0,0 +1,33 @@
+package parser
+
+import (
+	"strings"
+)
+
+// osNoise adalah potongan teks yang dibuang saat menormalkan nama OS
+var osNoise = []string{"microsoft", "(r)", "®", "(tm)", "™", ","}
+
+// NormalizeOSName menyamakan format nama OS dari OCS ("Microsoft Windows 10 Pro")
+// dan AD ("Windows 10 Pro (10.0 (19045))") menjadi "windows 10 pro" agar bisa dibandingkan.
+func NormalizeOSName(name string) string {
+	s := strings.ToLower(name)
+	for _, noise := range osNoise {
+		s = strings.ReplaceAll(s, noise, " ")
+	}
+	// Buang versi di dalam kurung, termasuk kurung bersarang
+	var b strings.Builder
+	depth := 0
+	for _, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			if depth > 0 {
+				depth--
+			}
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,227 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Status siklus hidup komputer
+const (
+	LifecycleActive    = "active"
+	LifecycleStale     = "stale"
+	LifecycleAbandoned = "abandoned"
+)
+
+// Sumber waktu yang dinilai staleness-nya
+const (
+	StalenessADLastLogon      = "ad_last_logon"
+	StalenessOCSLastCome      = "ocs_last_come"
+	StalenessOCSLastInventory = "ocs_last_inventory"
+)
+
+// Batas bawaan jika tidak ada aturan yang mengatur sumber tersebut
+const (
+	defaultStalenessRuleName = "default"
+	defaultStaleDays         = 30
+	defaultAbandonedDays     = 90
+)
+
+// StalenessThreshold adalah batas umur (hari) untuk status stale dan abandoned
+type StalenessThreshold struct {
+	StaleDays     int `json:"stale_days"`
+	AbandonedDays int `json:"abandoned_days"`
+}
+
+// StalenessMatch menentukan komputer mana yang memakai sebuah aturan.
+// Semua kriteria yang diisi harus cocok; kriteria kosong dianggap cocok.
+type StalenessMatch struct {
+	Roles        []string `json:"roles,omitempty"`         // workstation, server, dc
+	OUSuffixes   []string `json:"ou_suffixes,omitempty"`   // mis. "OU=Laptop,DC=corp,DC=local"
+	Domains      []string `json:"domains,omitempty"`       // label sumber AD
+	NamePatterns []string `json:"name_patterns,omitempty"` // pola glob nama komputer, mis. "SRV-*"
+}
+
+// StalenessRule adalah satu aturan bernama dengan batas per sumber waktu
+type StalenessRule struct {
+	Name       string                        `json:"name"`
+	Match      StalenessMatch                `json:"match"`
+	Thresholds map[string]StalenessThreshold `json:"thresholds"` // sumber -> batas; sumber yang tidak diisi memakai "*" atau default
+}
+
+// StalenessPolicy adalah daftar aturan; aturan pertama yang cocok dipakai
+type StalenessPolicy struct {
+	Rules []StalenessRule `json:"rules"`
+}
+
+// stalenessPolicy adalah kebijakan aktif, diganti lewat SetStalenessPolicy saat startup
+var stalenessPolicy = StalenessPolicy{}
+
+// SetStalenessPolicy mengganti kebijakan staleness yang dipakai CombineOCSAndAD
+func SetStalenessPolicy(p StalenessPolicy) {
+	stalenessPolicy = p
+}
+
+// LoadStalenessPolicy membaca kebijakan dari file STALENESS_RULES_FILE atau JSON di STALENESS_RULES.
+// Tanpa keduanya dipakai satu aturan default: stale setelah 30 hari, abandoned setelah 90 hari.
+func LoadStalenessPolicy() (StalenessPolicy, error) {
+	var p StalenessPolicy
+	var raw []byte
+	if file := os.Getenv("STALENESS_RULES_FILE"); file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return p, fmt.Errorf("gagal membaca %s: %v", file, err)
+		}
+		raw = b
+	} else if s := os.Getenv("STALENESS_RULES"); s != "" {
+		raw = []byte(s)
+	} else {
+		return p, nil
+	}
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return p, fmt.Errorf("aturan staleness tidak valid: %v", err)
+	}
+	for i, r := range p.Rules {
+		if r.Name == "" {
+			return p, fmt.Errorf("aturan staleness ke-%d tidak memiliki nama", i+1)
+		}
+		for src, t := range r.Thresholds {
+			if !validStalenessSource(src) {
+				return p, fmt.Errorf("aturan staleness %q: sumber %q tidak dikenal (tersedia: %s, %s, %s, *)",
+					r.Name, src, StalenessADLastLogon, StalenessOCSLastCome, StalenessOCSLastInventory)
+			}
+			if t.StaleDays <= 0 || t.AbandonedDays < t.StaleDays {
+				return p, fmt.Errorf("aturan staleness %q sumber %q: stale_days harus > 0 dan abandoned_days >= stale_days", r.Name, src)
+			}
+		}
+	}
+	return p, nil
+}
+
+// validStalenessSource memeriksa kunci thresholds; "*" berlaku untuk semua sumber
+func validStalenessSource(src string) bool {
+	switch src {
+	case StalenessADLastLogon, StalenessOCSLastCome, StalenessOCSLastInventory, "*":
+		return true
+	}
+	return false
+}
+
+// matches memeriksa apakah baris cocok dengan kriteria aturan
+func (m StalenessMatch) matches(r *FinalComputerRow) bool {
+	if len(m.Roles) > 0 && !containsFold(m.Roles, r.ADRole) {
+		return false
+	}
+	if len(m.Domains) > 0 && !containsFold(m.Domains, r.ADDomain) {
+		return false
+	}
+	if len(m.OUSuffixes) > 0 {
+		ou := strings.ToLower(r.ADOU)
+		found := false
+		for _, suffix := range m.OUSuffixes {
+			if ou != "" && strings.HasSuffix(ou, strings.ToLower(suffix)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(m.NamePatterns) > 0 {
+		name := strings.ToLower(r.ComputerName)
+		found := false
+		for _, pattern := range m.NamePatterns {
+			if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// threshold mengembalikan batas untuk sumber tertentu pada aturan ini
+func (r StalenessRule) threshold(source string) StalenessThreshold {
+	if t, ok := r.Thresholds[source]; ok {
+		return t
+	}
+	if t, ok := r.Thresholds["*"]; ok {
+		return t
+	}
+	return StalenessThreshold{StaleDays: defaultStaleDays, AbandonedDays: defaultAbandonedDays}
+}
+
+// ruleFor mengembalikan aturan pertama yang cocok, atau aturan default
+func (p StalenessPolicy) ruleFor(r *FinalComputerRow) StalenessRule {
+	for _, rule := range p.Rules {
+		if rule.Match.matches(r) {
+			return rule
+		}
+	}
+	return StalenessRule{Name: defaultStalenessRuleName}
+}
+
+// stalenessState menilai umur waktu terhadap batas; waktu kosong dianggap abandoned
+func stalenessState(now time.Time, t *time.Time, th StalenessThreshold) string {
+	if t == nil {
+		return LifecycleAbandoned
+	}
+	days := now.Sub(*t).Hours() / 24
+	switch {
+	case days > float64(th.AbandonedDays):
+		return LifecycleAbandoned
+	case days > float64(th.StaleDays):
+		return LifecycleStale
+	default:
+		return LifecycleActive
+	}
+}
+
+// lifecycleRank mengurutkan status dari yang paling segar
+var lifecycleRank = map[string]int{LifecycleActive: 0, LifecycleStale: 1, LifecycleAbandoned: 2}
+
+// applyStaleness mengisi Staleness, StalenessRule dan LifecycleState.
+// Status akhir adalah yang paling segar di antara sumber tempat komputer terdaftar,
+// karena satu sumber yang masih melapor sudah cukup menandakan komputer masih dipakai.
+func (r *FinalComputerRow) applyStaleness(p StalenessPolicy, now time.Time) {
+	rule := p.ruleFor(r)
+	r.StalenessRule = rule.Name
+	r.Staleness = make(map[string]string, 3)
+
+	if r.ExistsInAD {
+		// Komputer yang belum pernah login dinilai dari waktu pembuatannya
+		ref := r.ADLastLogonTime
+		if ref == nil {
+			ref = r.ADWhenCreated
+		}
+		r.Staleness[StalenessADLastLogon] = stalenessState(now, ref, rule.threshold(StalenessADLastLogon))
+	}
+	if r.ExistsInOCS {
+		r.Staleness[StalenessOCSLastCome] = stalenessState(now, r.OCSLastCome, rule.threshold(StalenessOCSLastCome))
+		r.Staleness[StalenessOCSLastInventory] = stalenessState(now, r.OCSLastInventory, rule.threshold(StalenessOCSLastInventory))
+	}
+
+	r.LifecycleState = LifecycleAbandoned
+	for _, state := range r.Staleness {
+		if lifecycleRank[state] < lifecycleRank[r.LifecycleState] {
+			r.LifecycleState = state
+		}
+	}
+}
+
+// containsFold memeriksa keberadaan s di list tanpa membedakan huruf besar/kecil
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadStalenessPolicyThresholdKeys(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		rules   string
+		wantErr string
+	}{
+		{
+			name:  "sumber dikenal",
+			rules: `{"rules":[{"name":"server","thresholds":{"ad_last_logon":{"stale_days":60,"abandoned_days":90},"ocs_last_come":{"stale_days":60,"abandoned_days":90},"ocs_last_inventory":{"stale_days":60,"abandoned_days":120},"*":{"stale_days":30,"abandoned_days":90}}}]}`,
+		},
+		{
+			name:    "salah ketik sumber",
+			rules:   `{"rules":[{"name":"laptop","thresholds":{"ad_lastlogon":{"stale_days":30,"abandoned_days":90}}}]}`,
+			wantErr: `sumber "ad_lastlogon" tidak dikenal`,
+		},
+		{
+			name:    "batas tidak valid",
+			rules:   `{"rules":[{"name":"laptop","thresholds":{"*":{"stale_days":30,"abandoned_days":10}}}]}`,
+			wantErr: "abandoned_days >= stale_days",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("STALENESS_RULES_FILE", "")
+			t.Setenv("STALENESS_RULES", tc.rules)
+			_, err := LoadStalenessPolicy()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("error tidak diharapkan: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("error = %v, want mengandung %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestStalenessPolicyRuleFor(t *testing.T) {
+	policy := StalenessPolicy{Rules: []StalenessRule{
+		{Name: "dc", Match: StalenessMatch{Roles: []string{"dc"}}},
+		{Name: "server-corp", Match: StalenessMatch{Roles: []string{"server"}, Domains: []string{"corp"}}},
+		{Name: "laptop", Match: StalenessMatch{OUSuffixes: []string{"OU=Laptop,DC=corp,DC=local"}}},
+		{Name: "srv-name", Match: StalenessMatch{NamePatterns: []string{"SRV-*"}}},
+		{Name: "server", Match: StalenessMatch{Roles: []string{"server"}}},
+	}}
+
+	for _, tc := range []struct {
+		name string
+		row  FinalComputerRow
+		want string
+	}{
+		{name: "role dc", row: FinalComputerRow{ComputerName: "DC01", ADRole: "dc"}, want: "dc"},
+		{name: "role dan domain", row: FinalComputerRow{ComputerName: "APP01", ADRole: "server", ADDomain: "CORP"}, want: "server-corp"},
+		{name: "role cocok, domain lain", row: FinalComputerRow{ComputerName: "APP01", ADRole: "server", ADDomain: "lab"}, want: "server"},
+		{name: "akhiran OU", row: FinalComputerRow{ComputerName: "LT01", ADRole: "workstation", ADOU: "OU=Sales,OU=Laptop,DC=corp,DC=local"}, want: "laptop"},
+		{name: "OU lain", row: FinalComputerRow{ComputerName: "PC01", ADRole: "workstation", ADOU: "OU=Desktop,DC=corp,DC=local"}, want: defaultStalenessRuleName},
+		{name: "pola nama", row: FinalComputerRow{ComputerName: "srv-backup"}, want: "srv-name"},
+		{name: "aturan pertama menang", row: FinalComputerRow{ComputerName: "SRV-APP", ADRole: "server", ADDomain: "corp"}, want: "server-corp"},
+		{name: "tanpa aturan cocok", row: FinalComputerRow{ComputerName: "PC02"}, want: defaultStalenessRuleName},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := policy.ruleFor(&tc.row).Name; got != tc.want {
+				t.Errorf("aturan = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyStaleness(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(d int) *time.Time {
+		v := now.AddDate(0, 0, -d)
+		return &v
+	}
+	policy := StalenessPolicy{Rules: []StalenessRule{{
+		Name:  "server",
+		Match: StalenessMatch{Roles: []string{"server"}},
+		Thresholds: map[string]StalenessThreshold{
+			StalenessADLastLogon: {StaleDays: 60, AbandonedDays: 90},
+			"*":                  {StaleDays: 10, AbandonedDays: 20},
+		},
+	}}}
+
+	for _, tc := range []struct {
+		name          string
+		row           FinalComputerRow
+		wantRule      string
+		wantStaleness map[string]string
+		wantLifecycle string
+	}{
+		{
+			name:          "default hanya AD",
+			row:           FinalComputerRow{ExistsInAD: true, ADLastLogonTime: daysAgo(45)},
+			wantRule:      defaultStalenessRuleName,
+			wantStaleness: map[string]string{StalenessADLastLogon: LifecycleStale},
+			wantLifecycle: LifecycleStale,
+		},
+		{
+			name:          "belum pernah login dinilai dari whenCreated",
+			row:           FinalComputerRow{ExistsInAD: true, ADWhenCreated: daysAgo(5)},
+			wantRule:      defaultStalenessRuleName,
+			wantStaleness: map[string]string{StalenessADLastLogon: LifecycleActive},
+			wantLifecycle: LifecycleActive,
+		},
+		{
+			name:          "waktu kosong dianggap abandoned",
+			row:           FinalComputerRow{ExistsInOCS: true, OCSLastCome: daysAgo(1)},
+			wantRule:      defaultStalenessRuleName,
+			wantStaleness: map[string]string{StalenessOCSLastCome: LifecycleActive, StalenessOCSLastInventory: LifecycleAbandoned},
+			wantLifecycle: LifecycleActive,
+		},
+		{
+			name: "batas per sumber dan wildcard",
+			row: FinalComputerRow{
+				ADRole: "server", ExistsInAD: true, ExistsInOCS: true,
+				ADLastLogonTime: daysAgo(70), OCSLastCome: daysAgo(15), OCSLastInventory: daysAgo(30),
+			},
+			wantRule:      "server",
+			wantStaleness: map[string]string{StalenessADLastLogon: LifecycleStale, StalenessOCSLastCome: LifecycleStale, StalenessOCSLastInventory: LifecycleAbandoned},
+			wantLifecycle: LifecycleStale,
+		},
+		{
+			name: "semua sumber abandoned",
+			row: FinalComputerRow{
+				ExistsInAD: true, ExistsInOCS: true,
+				ADLastLogonTime: daysAgo(100), OCSLastCome: daysAgo(95), OCSLastInventory: daysAgo(120),
+			},
+			wantRule:      defaultStalenessRuleName,
+			wantStaleness: map[string]string{StalenessADLastLogon: LifecycleAbandoned, StalenessOCSLastCome: LifecycleAbandoned, StalenessOCSLastInventory: LifecycleAbandoned},
+			wantLifecycle: LifecycleAbandoned,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			row := tc.row
+			row.applyStaleness(policy, now)
+			if row.StalenessRule != tc.wantRule {
+				t.Errorf("StalenessRule = %q, want %q", row.StalenessRule, tc.wantRule)
+			}
+			if len(row.Staleness) != len(tc.wantStaleness) {
+				t.Errorf("Staleness = %v, want %v", row.Staleness, tc.wantStaleness)
+			}
+			for src, want := range tc.wantStaleness {
+				if got := row.Staleness[src]; got != want {
+					t.Errorf("Staleness[%s] = %q, want %q", src, got, want)
+				}
+			}
+			if row.LifecycleState != tc.wantLifecycle {
+				t.Errorf("LifecycleState = %q, want %q", row.LifecycleState, tc.wantLifecycle)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"fmt"
+	"time"
+)
+
+// displayLocation adalah zona waktu untuk field tanggal yang dikirim ke Elasticsearch.
+// Semua waktu internal disimpan dalam UTC; zona ini hanya dipakai saat format output.
+var displayLocation = time.FixedZone("WIB", 7*60*60)
+
+// SetDisplayTimezone mengganti zona waktu tampilan, mis. "Asia/Jakarta" atau "Asia/Makassar".
+// Nama kosong mempertahankan default WIB (UTC+7).
+func SetDisplayTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("zona waktu %q tidak dikenal: %v", name, err)
+	}
+	displayLocation = loc
+	return nil
+}
+
+// DisplayLocation mengembalikan zona waktu tampilan yang aktif
+func DisplayLocation() *time.Location {
+	return displayLocation
+}
+
+// displayTime mengembalikan waktu dalam zona tampilan sehingga JSON-nya berupa RFC3339 dengan offset.
+// Waktu kosong (zero) menghasilkan nil agar dikirim sebagai null ke Elasticsearch.
+func displayTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.In(displayLocation)
+	return &t
+}
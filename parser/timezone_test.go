@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// setDisplayTimezone mengganti zona tampilan selama test dan mengembalikannya setelah selesai
+func setDisplayTimezone(t *testing.T, name string) {
+	t.Helper()
+	prev := displayLocation
+	t.Cleanup(func() { displayLocation = prev })
+	if err := SetDisplayTimezone(name); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConvertLDAPTimestamp(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"116444736000000000", time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"132539328000000000", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"132539328001234567", time.Date(2021, 1, 1, 0, 0, 0, 123456700, time.UTC)},
+		{"133537248000000000", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		// Nilai kosong/0 berarti tidak pernah, bukan 1601-01-01
+		{"", time.Time{}},
+		{"0", time.Time{}},
+		{"-1", time.Time{}},
+		{"bukan-angka", time.Time{}},
+	}
+	for _, tt := range tests {
+		got := convertLDAPTimestamp(tt.in)
+		if !got.Equal(tt.want) || got.IsZero() != tt.want.IsZero() {
+			t.Errorf("convertLDAPTimestamp(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+		if !got.IsZero() && got.Location() != time.UTC {
+			t.Errorf("convertLDAPTimestamp(%q) zona %v, want UTC", tt.in, got.Location())
+		}
+	}
+}
+
+func TestFiletimeToTime(t *testing.T) {
+	// Hasil tidak bergantung zona tampilan
+	setDisplayTimezone(t, "Asia/Makassar")
+	got := filetimeToTime(132539328000000000)
+	want := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !got.Equal(want) || got.Location() != time.UTC {
+		t.Errorf("filetimeToTime = %v, want %v", got, want)
+	}
+}
+
+func TestParseGeneralizedTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"20240131120000.0Z", time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)},
+		{"20240131120000Z", time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)},
+		{"20240131120000.123Z", time.Date(2024, 1, 31, 12, 0, 0, 123000000, time.UTC)},
+		// 17:30 UTC sudah tanggal 1 di WIB/WITA, tetapi internal tetap UTC
+		{"20231231173000.0Z", time.Date(2023, 12, 31, 17, 30, 0, 0, time.UTC)},
+		{"", time.Time{}},
+		{"2024-01-31", time.Time{}},
+	}
+	for _, tt := range tests {
+		got := parseGeneralizedTime(tt.in)
+		if !got.Equal(tt.want) || got.IsZero() != tt.want.IsZero() {
+			t.Errorf("parseGeneralizedTime(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayTime(t *testing.T) {
+	setDisplayTimezone(t, "Asia/Makassar")
+
+	tests := []struct {
+		in       time.Time
+		wantJSON string // "null" untuk waktu kosong
+	}{
+		{time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), `"2021-01-01T08:00:00+08:00"`},
+		{time.Date(2023, 12, 31, 17, 30, 0, 0, time.UTC), `"2024-01-01T01:30:00+08:00"`},
+		// Waktu dari zona lain dikonversi, bukan sekadar diganti label zonanya
+		{time.Date(2024, 6, 1, 9, 0, 0, 0, time.FixedZone("WIB", 7*3600)), `"2024-06-01T10:00:00+08:00"`},
+		{time.Time{}, `null`},
+	}
+	for _, tt := range tests {
+		got := displayTime(tt.in)
+		if tt.in.IsZero() != (got == nil) {
+			t.Errorf("displayTime(%v) = %v", tt.in, got)
+			continue
+		}
+		b, err := json.Marshal(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.wantJSON {
+			t.Errorf("displayTime(%v) JSON = %s, want %s", tt.in, b, tt.wantJSON)
+		}
+	}
+}
+
+func TestSetDisplayTimezoneDefaultAndInvalid(t *testing.T) {
+	setDisplayTimezone(t, "")
+	if _, offset := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).In(DisplayLocation()).Zone(); offset != 7*3600 {
+		t.Errorf("offset default = %d, want WIB (+7)", offset)
+	}
+	if err := SetDisplayTimezone("Asia/Tidak_Ada"); err == nil {
+		t.Error("zona tidak dikenal harus ditolak")
+	}
+}
+
+func TestSyncTimestamp(t *testing.T) {
+	setDisplayTimezone(t, "Asia/Makassar")
+	ocs := time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)
+	ad := time.Date(2024, 2, 1, 2, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		ocs, ad  time.Time
+		want     time.Time
+		wantZone int
+	}{
+		{"OCS diutamakan", ocs, ad, ocs, 8 * 3600},
+		{"fallback AD", time.Time{}, ad, ad, 8 * 3600},
+	}
+	for _, tt := range tests {
+		got := syncTimestamp(tt.ocs, tt.ad)
+		if !got.Equal(tt.want) {
+			t.Errorf("%s: syncTimestamp = %v, want %v", tt.name, got, tt.want)
+		}
+		if _, offset := got.Zone(); offset != tt.wantZone {
+			t.Errorf("%s: offset = %d, want %d", tt.name, offset, tt.wantZone)
+		}
+	}
+
+	// Keduanya kosong: waktu sekarang dalam zona tampilan
+	before := time.Now()
+	got := syncTimestamp(time.Time{}, time.Time{})
+	if got.Before(before.Add(-time.Second)) || got.After(time.Now().Add(time.Second)) {
+		t.Errorf("syncTimestamp kosong = %v, want sekitar sekarang", got)
+	}
+	if got.Location() != DisplayLocation() {
+		t.Errorf("syncTimestamp kosong zona %v, want %v", got.Location(), DisplayLocation())
+	}
+}
@@ -0,0 +1,167 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+
+	"ocs-ad-inventorymanagement/parser"
+)
+
+// Jenis ChangeEvent
+const (
+	EventAdded            = "added"
+	EventRemoved          = "removed"
+	EventAppeared         = "appeared"
+	EventDisappeared      = "disappeared"
+	EventBecameDisabled   = "became_disabled"
+	EventBecameEnabled    = "became_enabled"
+	EventLifecycleChanged = "lifecycle_changed"
+	EventCrossedInactive  = "crossed_inactive" // Satu sumber melewati batas stale dari kebijakan staleness
+)
+
+// Sumber data yang disebut di ChangeEvent
+const (
+	SourceAD  = "ad"
+	SourceOCS = "ocs"
+)
+
+// ChangeEvent adalah satu perubahan inventaris yang terdeteksi antar siklus
+type ChangeEvent struct {
+	Type         string    `json:"type"`
+	Source       string    `json:"source,omitempty"` // ad atau ocs; kosong untuk added/removed
+	DocumentID   string    `json:"document_id"`
+	ComputerName string    `json:"computer_name"`
+	Domain       string    `json:"ad_domain,omitempty"`
+	From         string    `json:"from,omitempty"` // status sebelumnya, untuk lifecycle_changed dan crossed_inactive
+	To           string    `json:"to,omitempty"`   // status baru, untuk lifecycle_changed dan crossed_inactive
+	Time         time.Time `json:"time"`
+}
+
+// EventSink menerima ChangeEvent hasil satu siklus
+type EventSink interface {
+	Publish(ctx context.Context, events []ChangeEvent) error
+}
+
+// LogEventSink menulis setiap ChangeEvent sebagai satu baris JSON di log
+type LogEventSink struct{}
+
+// Publish menulis event ke log
+func (LogEventSink) Publish(_ context.Context, events []ChangeEvent) error {
+	for _, ev := range events {
+		b, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		log.Printf("[EVENT] %s", b)
+	}
+	return nil
+}
+
+// detectChanges membandingkan dokumen sebelumnya (per document ID) dengan hasil gabungan terbaru.
+// Baris yang ID-nya tidak ada di prev dicocokkan berdasarkan nama dan domain (lihat matchPrevious),
+// sehingga komputer yang muncul/hilang dari AD berlabel domain tidak dilaporkan sebagai removed + added.
+func detectChanges(prev map[string]parser.FinalComputerRow, rows []parser.FinalComputerRow, now time.Time) []ChangeEvent {
+	var events []ChangeEvent
+	seen := make(map[string]struct{}, len(rows))
+	matched := matchPrevious(prev, rows)
+
+	for i, row := range rows {
+		id := row.DocumentID()
+		if matched[i] != "" {
+			seen[matched[i]] = struct{}{}
+		}
+		emitTransition := func(typ, source, from, to string) {
+			events = append(events, ChangeEvent{
+				Type:         typ,
+				Source:       source,
+				DocumentID:   id,
+				ComputerName: row.ComputerName,
+				Domain:       row.ADDomain,
+				From:         from,
+				To:           to,
+				Time:         now,
+			})
+		}
+		emit := func(typ, source string) {
+			emitTransition(typ, source, "", "")
+		}
+
+		old, ok := prev[matched[i]]
+		if !ok {
+			emit(EventAdded, "")
+			continue
+		}
+
+		// Muncul/hilang dari masing-masing sumber
+		if !old.ExistsInAD && row.ExistsInAD {
+			emit(EventAppeared, SourceAD)
+		}
+		if old.ExistsInAD && !row.ExistsInAD {
+			emit(EventDisappeared, SourceAD)
+		}
+		if !old.ExistsInOCS && row.ExistsInOCS {
+			emit(EventAppeared, SourceOCS)
+		}
+		if old.ExistsInOCS && !row.ExistsInOCS {
+			emit(EventDisappeared, SourceOCS)
+		}
+
+		// Perubahan status enabled/disabled, hanya jika komputer ada di sumber tersebut pada kedua siklus
+		if old.ExistsInAD && row.ExistsInAD && old.ADStatus != row.ADStatus {
+			if row.ADStatus == "disabled" {
+				emit(EventBecameDisabled, SourceAD)
+			} else if old.ADStatus == "disabled" {
+				emit(EventBecameEnabled, SourceAD)
+			}
+		}
+		if old.ExistsInOCS && row.ExistsInOCS && old.OCSStatus != row.OCSStatus {
+			if row.OCSStatus == "disabled" {
+				emit(EventBecameDisabled, SourceOCS)
+			} else if old.OCSStatus == "disabled" {
+				emit(EventBecameEnabled, SourceOCS)
+			}
+		}
+
+		// Melewati batas tidak aktif per sumber (stale_days aturan yang berlaku)
+		if from, to := old.Staleness[parser.StalenessADLastLogon], row.Staleness[parser.StalenessADLastLogon]; crossedInactive(from, to) {
+			emitTransition(EventCrossedInactive, SourceAD, from, to)
+		}
+		if from, to := old.Staleness[parser.StalenessOCSLastCome], row.Staleness[parser.StalenessOCSLastCome]; crossedInactive(from, to) {
+			emitTransition(EventCrossedInactive, SourceOCS, from, to)
+		}
+
+		// Perubahan status lifecycle; dokumen lama tanpa lifecycle_state (sebelum kebijakan staleness) dilewati
+		if old.LifecycleState != "" && old.LifecycleState != row.LifecycleState {
+			emitTransition(EventLifecycleChanged, "", old.LifecycleState, row.LifecycleState)
+		}
+	}
+
+	// Dokumen lama yang tidak ada lagi di OCS maupun AD
+	var removed []string
+	for id := range prev {
+		if _, ok := seen[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+	for _, id := range removed {
+		old := prev[id]
+		events = append(events, ChangeEvent{
+			Type:         EventRemoved,
+			DocumentID:   id,
+			ComputerName: old.ComputerName,
+			Domain:       old.ADDomain,
+			Time:         now,
+		})
+	}
+	return events
+}
+
+// crossedInactive memeriksa perpindahan staleness satu sumber dari active ke stale/abandoned.
+// Nilai kosong (dokumen lama sebelum kebijakan staleness, atau sumber tidak ada) tidak dihitung.
+func crossedInactive(from, to string) bool {
+	return from == parser.LifecycleActive && to != "" && to != parser.LifecycleActive
+}
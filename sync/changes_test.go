@@ -0,0 +1,168 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"ocs-ad-inventorymanagement/parser"
+)
+
+// changeKey meringkas ChangeEvent untuk dibandingkan di test
+type changeKey struct {
+	Type, Source, DocumentID, From, To string
+}
+
+func TestDetectChanges(t *testing.T) {
+	active := map[string]string{parser.StalenessADLastLogon: parser.LifecycleActive, parser.StalenessOCSLastCome: parser.LifecycleActive}
+	both := parser.FinalComputerRow{
+		ComputerName: "PC-01", ExistsInAD: true, ExistsInOCS: true, ADStatus: "enabled", OCSStatus: "enabled",
+		Staleness: active, LifecycleState: parser.LifecycleActive,
+	}
+	with := func(change func(r *parser.FinalComputerRow)) parser.FinalComputerRow {
+		r := both
+		r.Staleness = make(map[string]string, len(both.Staleness))
+		for k, v := range both.Staleness {
+			r.Staleness[k] = v
+		}
+		change(&r)
+		return r
+	}
+
+	for _, tc := range []struct {
+		name string
+		prev []parser.FinalComputerRow
+		rows []parser.FinalComputerRow
+		want []changeKey
+	}{
+		{
+			name: "tidak berubah",
+			prev: []parser.FinalComputerRow{both},
+			rows: []parser.FinalComputerRow{both},
+		},
+		{
+			name: "ditambah",
+			rows: []parser.FinalComputerRow{both},
+			want: []changeKey{{Type: EventAdded, DocumentID: "PC-01"}},
+		},
+		{
+			name: "dihapus",
+			prev: []parser.FinalComputerRow{both},
+			want: []changeKey{{Type: EventRemoved, DocumentID: "PC-01"}},
+		},
+		{
+			name: "muncul di AD",
+			prev: []parser.FinalComputerRow{with(func(r *parser.FinalComputerRow) { r.ExistsInAD = false; r.ADStatus = "" })},
+			rows: []parser.FinalComputerRow{both},
+			want: []changeKey{{Type: EventAppeared, Source: SourceAD, DocumentID: "PC-01"}},
+		},
+		{
+			name: "hilang dari OCS",
+			prev: []parser.FinalComputerRow{both},
+			rows: []parser.FinalComputerRow{with(func(r *parser.FinalComputerRow) { r.ExistsInOCS = false; r.OCSStatus = "" })},
+			want: []changeKey{{Type: EventDisappeared, Source: SourceOCS, DocumentID: "PC-01"}},
+		},
+		{
+			name: "dinonaktifkan di AD",
+			prev: []parser.FinalComputerRow{both},
+			rows: []parser.FinalComputerRow{with(func(r *parser.FinalComputerRow) { r.ADStatus = "disabled" })},
+			want: []changeKey{{Type: EventBecameDisabled, Source: SourceAD, DocumentID: "PC-01"}},
+		},
+		{
+			name: "diaktifkan di OCS",
+			prev: []parser.FinalComputerRow{with(func(r *parser.FinalComputerRow) { r.OCSStatus = "disabled" })},
+			rows: []parser.FinalComputerRow{both},
+			want: []changeKey{{Type: EventBecameEnabled, Source: SourceOCS, DocumentID: "PC-01"}},
+		},
+		{
+			name: "AD melewati batas stale",
+			prev: []parser.FinalComputerRow{both},
+			rows: []parser.FinalComputerRow{with(func(r *parser.FinalComputerRow) { r.Staleness[parser.StalenessADLastLogon] = parser.LifecycleStale })},
+			want: []changeKey{{Type: EventCrossedInactive, Source: SourceAD, DocumentID: "PC-01", From: parser.LifecycleActive, To: parser.LifecycleStale}},
+		},
+		{
+			name: "lifecycle active ke stale",
+			prev: []parser.FinalComputerRow{both},
+			rows: []parser.FinalComputerRow{with(func(r *parser.FinalComputerRow) {
+				r.Staleness[parser.StalenessADLastLogon] = parser.LifecycleStale
+				r.Staleness[parser.StalenessOCSLastCome] = parser.LifecycleAbandoned
+				r.LifecycleState = parser.LifecycleStale
+			})},
+			want: []changeKey{
+				{Type: EventCrossedInactive, Source: SourceAD, DocumentID: "PC-01", From: parser.LifecycleActive, To: parser.LifecycleStale},
+				{Type: EventCrossedInactive, Source: SourceOCS, DocumentID: "PC-01", From: parser.LifecycleActive, To: parser.LifecycleAbandoned},
+				{Type: EventLifecycleChanged, DocumentID: "PC-01", From: parser.LifecycleActive, To: parser.LifecycleStale},
+			},
+		},
+		{
+			name: "muncul di AD berlabel domain",
+			prev: []parser.FinalComputerRow{with(func(r *parser.FinalComputerRow) { r.ExistsInAD = false; r.ADStatus = "" })},
+			rows: []parser.FinalComputerRow{with(func(r *parser.FinalComputerRow) { r.ADDomain = "corp" })},
+			want: []changeKey{{Type: EventAppeared, Source: SourceAD, DocumentID: "PC-01@corp"}},
+		},
+		{
+			name: "hilang dari AD berlabel domain",
+			prev: []parser.FinalComputerRow{with(func(r *parser.FinalComputerRow) { r.ADDomain = "corp" })},
+			rows: []parser.FinalComputerRow{with(func(r *parser.FinalComputerRow) { r.ExistsInAD = false; r.ADStatus = "" })},
+			want: []changeKey{{Type: EventDisappeared, Source: SourceAD, DocumentID: "PC-01"}},
+		},
+		{
+			name: "nama sama di domain lain",
+			prev: []parser.FinalComputerRow{with(func(r *parser.FinalComputerRow) { r.ADDomain = "corp" })},
+			rows: []parser.FinalComputerRow{with(func(r *parser.FinalComputerRow) { r.ADDomain = "lab" })},
+			want: []changeKey{
+				{Type: EventAdded, DocumentID: "PC-01@lab"},
+				{Type: EventRemoved, DocumentID: "PC-01@corp"},
+			},
+		},
+		{
+			name: "dua domain, satu dinonaktifkan",
+			prev: []parser.FinalComputerRow{
+				with(func(r *parser.FinalComputerRow) { r.ADDomain = "corp" }),
+				with(func(r *parser.FinalComputerRow) { r.ADDomain = "lab"; r.ExistsInOCS = false; r.OCSStatus = "" }),
+			},
+			rows: []parser.FinalComputerRow{
+				with(func(r *parser.FinalComputerRow) { r.ADDomain = "corp" }),
+				with(func(r *parser.FinalComputerRow) {
+					r.ADDomain = "lab"
+					r.ExistsInOCS = false
+					r.OCSStatus = ""
+					r.ADStatus = "disabled"
+				}),
+			},
+			want: []changeKey{{Type: EventBecameDisabled, Source: SourceAD, DocumentID: "PC-01@lab"}},
+		},
+		{
+			name: "dokumen lama tanpa staleness",
+			prev: []parser.FinalComputerRow{with(func(r *parser.FinalComputerRow) { r.Staleness = nil; r.LifecycleState = "" })},
+			rows: []parser.FinalComputerRow{with(func(r *parser.FinalComputerRow) {
+				r.Staleness[parser.StalenessADLastLogon] = parser.LifecycleStale
+				r.LifecycleState = parser.LifecycleStale
+			})},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			prev := make(map[string]parser.FinalComputerRow, len(tc.prev))
+			for _, r := range tc.prev {
+				prev[r.DocumentID()] = r
+			}
+			now := time.Now()
+
+			events := detectChanges(prev, tc.rows, now)
+			var got []changeKey
+			for _, ev := range events {
+				if !ev.Time.Equal(now) {
+					t.Errorf("event %+v: Time = %v, want %v", ev, ev.Time, now)
+				}
+				got = append(got, changeKey{ev.Type, ev.Source, ev.DocumentID, ev.From, ev.To})
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("events = %+v, want %+v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("event[%d] = %+v, want %+v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package sync
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule adalah jadwal cron 5 field: menit jam hari-bulan bulan hari-minggu.
+// Mendukung "*", angka, rentang "a-b", langkah "*/n" atau "a-b/n" dan daftar "a,b,c".
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+	loc                           *time.Location
+}
+
+type cronField struct {
+	min, max int
+}
+
+var cronFields = []cronField{
+	{0, 59}, // menit
+	{0, 23}, // jam
+	{1, 31}, // hari dalam bulan
+	{1, 12}, // bulan
+	{0, 6},  // hari dalam minggu (0 = Minggu)
+}
+
+// ParseCron mem-parsing ekspresi cron 5 field
+func ParseCron(expr string, loc *time.Location) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("ekspresi cron %q harus terdiri dari 5 field", expr)
+	}
+	var bits [5]uint64
+	for i, f := range fields {
+		b, err := parseCronField(f, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("ekspresi cron %q: %v", expr, err)
+		}
+		bits[i] = b
+	}
+	// Hari Minggu boleh ditulis 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	return &cronSchedule{
+		minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		domStar: cronUnrestricted(fields[2], bits[2], cronFields[2]),
+		dowStar: cronUnrestricted(fields[4], bits[4], cronFields[4]),
+		loc:     loc,
+	}, nil
+}
+
+// cronUnrestricted menentukan apakah field hari tidak membatasi apa pun untuk aturan OR di dayMatches:
+// field yang diawali "*" (mis. "*" atau "*/1", seperti cron standar) atau yang mencakup seluruh rentang (mis. "1-31")
+func cronUnrestricted(field string, bits uint64, r cronField) bool {
+	if strings.HasPrefix(field, "*") {
+		return true
+	}
+	for v := r.min; v <= r.max; v++ {
+		if bits&(1<<uint(v)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func parseCronField(field string, r cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("langkah tidak valid pada %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+		lo, hi := r.min, r.max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			ab := strings.SplitN(part, "-", 2)
+			a, err1 := strconv.Atoi(ab[0])
+			b, err2 := strconv.Atoi(ab[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("rentang tidak valid %q", part)
+			}
+			lo, hi = a, b
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("nilai tidak valid %q", part)
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+		max := r.max
+		if r.max == 6 {
+			max = 7 // izinkan 7 untuk hari Minggu
+		}
+		if lo < r.min || hi > max || lo > hi {
+			return 0, fmt.Errorf("nilai %q di luar rentang %d-%d", part, r.min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next mengembalikan waktu eksekusi berikutnya setelah t
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	t = t.In(c.loc).Truncate(time.Minute).Add(time.Minute)
+	// Batasi pencarian hingga 5 tahun untuk ekspresi yang tidak pernah cocok (mis. 31 Februari)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.loc)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches mengikuti aturan cron standar: jika hari-bulan dan hari-minggu
+// sama-sama dibatasi, cukup salah satu yang cocok.
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	domOK := c.dom&(1<<uint(t.Day())) != 0
+	dowOK := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
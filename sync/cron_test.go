@@ -0,0 +1,94 @@
+package sync
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, tc := range []struct {
+		expr    string
+		wantErr string
+	}{
+		{expr: "*/15 * * * *"},
+		{expr: "0 2 * * 1-5"},
+		{expr: "0 0 1,15 * 7"},
+		{expr: "30 6 1-31/2 1-12 0-7"},
+		{expr: "* * * *", wantErr: "5 field"},
+		{expr: "60 * * * *", wantErr: "di luar rentang 0-59"},
+		{expr: "0 24 * * *", wantErr: "di luar rentang 0-23"},
+		{expr: "0 0 0 * *", wantErr: "di luar rentang 1-31"},
+		{expr: "0 0 * 13 *", wantErr: "di luar rentang 1-12"},
+		{expr: "0 0 * * 8", wantErr: "di luar rentang 0-7"},
+		{expr: "0 0 * * 5-1", wantErr: "di luar rentang"},
+		{expr: "*/0 * * * *", wantErr: "langkah tidak valid"},
+		{expr: "a * * * *", wantErr: "nilai tidak valid"},
+		{expr: "1-x * * * *", wantErr: "rentang tidak valid"},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := ParseCron(tc.expr, time.UTC)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("error tidak diharapkan: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("error = %v, want mengandung %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// Kamis, 15 Januari 2026 10:07:30 UTC
+	from := time.Date(2026, 1, 15, 10, 7, 30, 0, time.UTC)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	for _, tc := range []struct {
+		expr string
+		want time.Time
+	}{
+		{expr: "* * * * *", want: at(1, 15, 10, 8)},
+		{expr: "*/15 * * * *", want: at(1, 15, 10, 15)},
+		{expr: "0 2 * * *", want: at(1, 16, 2, 0)},
+		{expr: "0 9 * * 1-5", want: at(1, 16, 9, 0)},
+		{expr: "0 0 * * 0", want: at(1, 18, 0, 0)},
+		{expr: "0 0 * * 7", want: at(1, 18, 0, 0)},
+		{expr: "0 0 1 * *", want: at(2, 1, 0, 0)},
+		{expr: "0 0 * 3 *", want: at(3, 1, 0, 0)},
+		// Hari-bulan dan hari-minggu sama-sama dibatasi: cukup salah satu cocok (tgl 20 atau Sabtu)
+		{expr: "0 0 20 * 6", want: at(1, 17, 0, 0)},
+		// "*/1" dan rentang penuh tidak membatasi hari-bulan, sehingga hanya hari Senin yang cocok
+		{expr: "0 0 */1 * 1", want: at(1, 19, 0, 0)},
+		{expr: "0 0 1-31 * 1", want: at(1, 19, 0, 0)},
+		{expr: "0 0 * * 0-6", want: at(1, 16, 0, 0)},
+		{expr: "0 0 31 2 *", want: time.Time{}},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			sched, err := ParseCron(tc.expr, time.UTC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sched.Next(from); !got.Equal(tc.want) {
+				t.Errorf("Next = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCronNextLocation(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*3600)
+	sched, err := ParseCron("0 2 * * *", jakarta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 20:00 UTC = 03:00 WIB, jadwal berikutnya 02:00 WIB hari berikutnya = 19:00 UTC
+	got := sched.Next(time.Date(2026, 1, 15, 20, 0, 0, 0, time.UTC))
+	if want := time.Date(2026, 1, 16, 19, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+}
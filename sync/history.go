@@ -0,0 +1,157 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"ocs-ad-inventorymanagement/client"
+	"ocs-ad-inventorymanagement/parser"
+)
+
+// History menyimpan riwayat hasil gabungan untuk laporan tren
+type History interface {
+	Record(ctx context.Context, rows []parser.FinalComputerRow) (client.BulkResult, error)
+}
+
+// historyDocument adalah satu baris snapshot harian di data stream riwayat.
+// @timestamp berisi waktu snapshot; @timestamp asli baris disimpan di source_timestamp.
+type historyDocument struct {
+	parser.FinalComputerRow
+	Timestamp       time.Time `json:"@timestamp"`
+	SnapshotDate    string    `json:"snapshot_date"`
+	SourceTimestamp time.Time `json:"source_timestamp"`
+}
+
+// historyProperties adalah mapping data stream riwayat: mapping inventory ditambah field snapshot
+func historyProperties() map[string]string {
+	props := make(map[string]string, len(parser.ComputerIndexProperties)+2)
+	for field, typ := range parser.ComputerIndexProperties {
+		props[field] = typ
+	}
+	props["snapshot_date"] = "date"
+	props["source_timestamp"] = "date"
+	return props
+}
+
+// ElasticsearchHistory menambahkan snapshot harian setiap FinalComputerRow ke data stream.
+// Snapshot hanya ditulis sekali per hari (zona DISPLAY_TZ); ID dokumen deterministik
+// sehingga penulisan ulang setelah restart tidak menghasilkan duplikat.
+type ElasticsearchHistory struct {
+	Client    *client.ElasticsearchClient
+	Stream    string
+	Retention string
+	Rollover  string
+
+	ready   bool
+	lastDay string
+}
+
+// NewElasticsearchHistory membuat History untuk data stream yang dikonfigurasi di client
+func NewElasticsearchHistory(c *client.ElasticsearchClient) *ElasticsearchHistory {
+	return &ElasticsearchHistory{
+		Client:    c,
+		Stream:    c.Config.HistoryStream,
+		Retention: c.Config.HistoryRetention,
+		Rollover:  c.Config.HistoryRollover,
+	}
+}
+
+// ensure memasang ILM policy dan index template data stream riwayat
+func (h *ElasticsearchHistory) ensure(ctx context.Context) error {
+	if h.ready {
+		return nil
+	}
+	policy := h.Stream + "-policy"
+	if err := h.Client.PutRetentionPolicy(ctx, policy, rolloverAge(h.Rollover, h.Retention), h.Retention); err != nil {
+		return err
+	}
+	tmpl := client.IndexTemplate{
+		Patterns:   []string{h.Stream},
+		Properties: historyProperties(),
+		DataStream: true,
+		Settings:   map[string]interface{}{"index.lifecycle.name": policy},
+		// Lebih tinggi dari template inventory yang mungkin cocok dengan pola "<index>-*"
+		Priority: 300,
+	}
+	if err := h.Client.PutIndexTemplate(ctx, h.Stream, tmpl); err != nil {
+		return err
+	}
+	h.ready = true
+	log.Printf("[SUCCESS] Elasticsearch - Data stream riwayat %s siap (retensi %s, rollover %s)", h.Stream, h.Retention, rolloverAge(h.Rollover, h.Retention))
+	return nil
+}
+
+// rolloverAge membatasi umur rollover agar tidak melebihi retention. Index yang di-rollover
+// lebih lambat dari retention akan menahan data lama melewati masa simpannya.
+// Nilai yang tidak bisa dibaca dikirim apa adanya agar Elasticsearch yang menolaknya.
+func rolloverAge(rollover, retention string) string {
+	if rollover == "" {
+		rollover = "30d"
+	}
+	r, okR := parseTimeUnit(rollover)
+	ret, okRet := parseTimeUnit(retention)
+	if okR && okRet && r > ret {
+		return retention
+	}
+	return rollover
+}
+
+// parseTimeUnit membaca satuan waktu Elasticsearch (mis. "30d", "12h", "90m", "45s", "500ms")
+func parseTimeUnit(s string) (time.Duration, bool) {
+	units := []struct {
+		suffix string
+		d      time.Duration
+	}{
+		// "ms" sebelum "s" dan "m" agar tidak salah dipotong
+		{"ms", time.Millisecond}, {"d", 24 * time.Hour}, {"h", time.Hour}, {"m", time.Minute}, {"s", time.Second},
+	}
+	for _, u := range units {
+		if !strings.HasSuffix(s, u.suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(s, u.suffix))
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		return time.Duration(n) * u.d, true
+	}
+	return 0, false
+}
+
+// Record menulis snapshot hari ini jika belum pernah ditulis
+func (h *ElasticsearchHistory) Record(ctx context.Context, rows []parser.FinalComputerRow) (client.BulkResult, error) {
+	now := time.Now().In(parser.DisplayLocation())
+	day := now.Format("2006-01-02")
+	if day == h.lastDay {
+		return client.BulkResult{}, nil
+	}
+	if err := h.ensure(ctx); err != nil {
+		return client.BulkResult{}, err
+	}
+
+	ops := make([]client.BulkOperation, 0, len(rows))
+	for _, row := range rows {
+		body, err := json.Marshal(historyDocument{
+			FinalComputerRow: row,
+			Timestamp:        now,
+			SnapshotDate:     day,
+			SourceTimestamp:  row.SyncTime,
+		})
+		if err != nil {
+			log.Printf("[ERROR] Gagal encode riwayat %s: %v", row.ComputerName, err)
+			continue
+		}
+		ops = append(ops, client.BulkOperation{Action: "create", DocumentID: day + ":" + row.DocumentID(), Body: body})
+	}
+
+	res, err := h.Client.Bulk(ctx, h.Stream, ops)
+	// Hari ini baru dianggap selesai jika setiap dokumen terkonfirmasi tersimpan
+	if err == nil && res.Failed == 0 && res.Indexed == len(ops) {
+		h.lastDay = day
+	}
+	return res, err
+}
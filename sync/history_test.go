@@ -0,0 +1,21 @@
+package sync
+
+import "testing"
+
+func TestRolloverAge(t *testing.T) {
+	for _, tc := range []struct {
+		rollover, retention, want string
+	}{
+		{"30d", "365d", "30d"},
+		{"", "365d", "30d"},
+		{"30d", "7d", "7d"},
+		{"12h", "1d", "12h"},
+		{"48h", "1d", "1d"},
+		{"500ms", "1s", "500ms"},
+		{"30d", "tidak-valid", "30d"},
+	} {
+		if got := rolloverAge(tc.rollover, tc.retention); got != tc.want {
+			t.Errorf("rolloverAge(%q, %q) = %q, want %q", tc.rollover, tc.retention, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package sync
+
+import (
+	"sort"
+	"strings"
+
+	"ocs-ad-inventorymanagement/parser"
+)
+
+// matchPrevious mencari dokumen sebelumnya untuk setiap baris dan mengembalikan ID-nya ("" jika tidak ada).
+// Baris yang ID-nya sudah ada di prev memakai ID tersebut. Baris lain dicocokkan dengan dokumen lama
+// bernama sama yang domainnya tidak bertentangan (sama, atau salah satunya kosong), karena ID bawaan
+// berubah saat komputer OCS baru cocok dengan AD berlabel domain, atau sebaliknya.
+// Setiap dokumen lama hanya dipakai oleh satu baris.
+func matchPrevious(prev map[string]parser.FinalComputerRow, rows []parser.FinalComputerRow) []string {
+	matched := make([]string, len(rows))
+	if len(prev) == 0 {
+		return matched
+	}
+
+	used := make(map[string]struct{}, len(rows))
+	for i, row := range rows {
+		if _, ok := prev[row.DocumentID()]; ok {
+			matched[i] = row.DocumentID()
+			used[matched[i]] = struct{}{}
+		}
+	}
+
+	// Dokumen lama per nama, terurut agar hasil pencocokan deterministik
+	byName := make(map[string][]string)
+	for id, doc := range prev {
+		key := parser.HashComputerName(doc.ComputerName)
+		byName[key] = append(byName[key], id)
+	}
+	for _, ids := range byName {
+		sort.Strings(ids)
+	}
+
+	for i, row := range rows {
+		if matched[i] != "" {
+			continue
+		}
+		for _, id := range byName[parser.HashComputerName(row.ComputerName)] {
+			if _, taken := used[id]; taken {
+				continue
+			}
+			if d := prev[id].ADDomain; d == "" || row.ADDomain == "" || strings.EqualFold(d, row.ADDomain) {
+				matched[i] = id
+				used[id] = struct{}{}
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// assignDocumentIDs mempertahankan ID dokumen dari siklus sebelumnya untuk setiap baris
+func assignDocumentIDs(prev map[string]parser.FinalComputerRow, rows []parser.FinalComputerRow) {
+	for i, id := range matchPrevious(prev, rows) {
+		if id != "" {
+			rows[i].AssignedID = id
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package sync
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ocs-ad-inventorymanagement/client"
+	"ocs-ad-inventorymanagement/parser"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// adState adalah high-water mark sinkronisasi incremental satu sumber AD.
+// uSNChanged hanya berlaku untuk DC tertentu, sehingga Server dan InvocationID ikut disimpan;
+// jika salah satunya berubah, dilakukan full resync.
+type adState struct {
+	Server       string                              `json:"server"`
+	InvocationID string                              `json:"invocation_id"`
+	HighestUSN   int64                               `json:"highest_usn"`
+	LastFullSync time.Time                           `json:"last_full_sync"`
+	Rows         map[string]parser.ComputerReportRow `json:"rows"` // objectGUID -> baris
+}
+
+// adDirectory adalah operasi LDAP yang dipakai mode incremental; *client.LDAPClient memenuhinya
+type adDirectory interface {
+	DSAInfo(ctx context.Context) (client.DSAInfo, error)
+	SearchFunc(ctx context.Context, filter string, attributes []string, fn func(*ldap.Entry) error) error
+	SearchDeletedFunc(ctx context.Context, namingContext, filter string, attributes []string, fn func(*ldap.Entry) error) error
+}
+
+// statePath mengembalikan lokasi file state untuk sumber ini
+func (s *LDAPSource) statePath() string {
+	name := s.Config.Domain
+	if name == "" {
+		name = "default"
+	}
+	return filepath.Join(s.Config.StateDir, "ldap-state-"+name+".json")
+}
+
+// loadState membaca state dari disk jika belum ada di memori
+func (s *LDAPSource) loadState() *adState {
+	if s.state != nil {
+		return s.state
+	}
+	b, err := os.ReadFile(s.statePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[WARN] LDAP - Gagal membaca state incremental %s: %v", s.statePath(), err)
+		}
+		return nil
+	}
+	var st adState
+	if err := json.Unmarshal(b, &st); err != nil {
+		log.Printf("[WARN] LDAP - State incremental %s rusak, full resync: %v", s.statePath(), err)
+		return nil
+	}
+	s.state = &st
+	return s.state
+}
+
+// saveState menyimpan state ke disk secara atomik (tulis file sementara lalu rename)
+func (s *LDAPSource) saveState(st *adState) error {
+	s.state = st
+	if err := os.MkdirAll(s.Config.StateDir, 0o755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	tmp := s.statePath() + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.statePath())
+}
+
+// fetchIncremental mengambil hanya objek yang berubah sejak uSNChanged terakhir,
+// menerapkan penghapusan dari container Deleted Objects, lalu mengembalikan populasi lengkap.
+// server adalah DC yang melayani dir. Objek yang dipindah keluar SearchBase atau tidak lagi
+// cocok dengan filter baru hilang saat full resync.
+func (s *LDAPSource) fetchIncremental(ctx context.Context, dir adDirectory, server string) ([]parser.ComputerReportRow, error) {
+	// Baca USN sebelum pencarian agar perubahan selama pencarian tidak terlewat
+	info, err := dir.DSAInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	st := s.loadState()
+	reason := ""
+	switch {
+	case st == nil:
+		reason = "belum ada state"
+	case st.Server != server:
+		reason = fmt.Sprintf("DC berubah dari %s", st.Server)
+	case st.InvocationID != info.InvocationID:
+		reason = "invocationId DC berubah"
+	case time.Since(st.LastFullSync) >= s.Config.FullResyncInterval:
+		reason = "interval full resync tercapai"
+	case info.HighestCommittedUSN < st.HighestUSN:
+		reason = "USN DC lebih kecil dari high-water mark"
+	case s.deletedUnreadable:
+		reason = "Deleted Objects tidak dapat dibaca akun bind"
+	}
+
+	filter := s.Config.Filter
+	if filter == "" {
+		filter = "(objectClass=computer)"
+	}
+	attributes := incrementalAttributes(s.Config.Attributes)
+
+	if reason == "" {
+		next, deletedErr, err := s.applyIncremental(ctx, dir, st, info, filter, attributes)
+		switch {
+		case err != nil:
+			return nil, err
+		case deletedErr != nil:
+			// Akun bind biasa umumnya tidak boleh membaca CN=Deleted Objects. Tanpa daftar penghapusan
+			// state tidak bisa dipercaya, jadi siklus ini memakai full resync agar sinkronisasi tetap jalan.
+			if ctx.Err() != nil {
+				return nil, deletedErr
+			}
+			reason = "Deleted Objects tidak dapat dibaca"
+			if deletedObjectsDenied(deletedErr) {
+				// Kegagalan hak akses tidak akan sembuh sendiri, jangan coba incremental lagi
+				s.deletedUnreadable = true
+				log.Printf("[WARN] LDAP - Akun bind%s tidak boleh membaca Deleted Objects, incremental dinonaktifkan hingga restart: %v", s.domainLabel(), deletedErr)
+			} else {
+				log.Printf("[WARN] LDAP - Gagal membaca Deleted Objects%s, siklus ini memakai full resync: %v", s.domainLabel(), deletedErr)
+			}
+		default:
+			st = next
+		}
+	}
+
+	if reason != "" {
+		log.Printf("[INFO] LDAP - Full resync%s: %s", s.domainLabel(), reason)
+		rows := make(map[string]parser.ComputerReportRow)
+		err := dir.SearchFunc(ctx, filter, attributes, func(entry *ldap.Entry) error {
+			if row, ok := parser.ParseComputerEntry(entry); ok {
+				row.Domain = s.Config.Domain
+				rows[objectGUID(entry)] = row
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		st = &adState{
+			Server:       server,
+			InvocationID: info.InvocationID,
+			HighestUSN:   info.HighestCommittedUSN,
+			LastFullSync: time.Now().UTC(),
+			Rows:         rows,
+		}
+	}
+
+	if err := s.saveState(st); err != nil {
+		// State tetap dipakai dari memori; siklus berikutnya mencoba menyimpan lagi
+		log.Printf("[WARN] LDAP - Gagal menyimpan state incremental %s: %v", s.statePath(), err)
+	}
+
+	rows := make([]parser.ComputerReportRow, 0, len(st.Rows))
+	for _, row := range st.Rows {
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// incrementalAttributes mengembalikan atribut untuk mode incremental: objectGUID ditambahkan
+// sebagai kunci state, sedangkan lastLogon dibuang. lastLogon tidak direplikasi antar DC dan tidak
+// menaikkan uSNChanged, sehingga nilainya di state akan membeku hingga full resync berikutnya;
+// tanpa lastLogon, ParseComputerEntry memakai lastLogonTimestamp yang ikut menaikkan uSNChanged.
+// Dengan LASTLOGON_ALL_DCS, lastLogon tetap dibaca ulang dari semua DC setiap siklus.
+func incrementalAttributes(configured []string) []string {
+	attributes := make([]string, 0, len(configured)+1)
+	for _, attr := range configured {
+		if !strings.EqualFold(attr, "lastLogon") {
+			attributes = append(attributes, attr)
+		}
+	}
+	return append(attributes, "objectGUID")
+}
+
+// applyIncremental menerapkan perubahan sejak high-water mark ke salinan state.
+// Kegagalan membaca Deleted Objects dikembalikan terpisah (deletedErr) agar pemanggil bisa
+// beralih ke full resync; err berisi kegagalan lain seperti koneksi putus.
+func (s *LDAPSource) applyIncremental(ctx context.Context, dir adDirectory, st *adState, info client.DSAInfo, filter string, attributes []string) (next *adState, deletedErr, err error) {
+	since := st.HighestUSN + 1
+	changed, deleted := 0, 0
+	updated := make(map[string]parser.ComputerReportRow, len(st.Rows))
+	for k, v := range st.Rows {
+		updated[k] = v
+	}
+
+	changedFilter := fmt.Sprintf("(&%s(uSNChanged>=%d))", filter, since)
+	err = dir.SearchFunc(ctx, changedFilter, attributes, func(entry *ldap.Entry) error {
+		if row, ok := parser.ParseComputerEntry(entry); ok {
+			row.Domain = s.Config.Domain
+			updated[objectGUID(entry)] = row
+			changed++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deletedFilter := fmt.Sprintf("(&(isDeleted=TRUE)(objectClass=computer)(uSNChanged>=%d))", since)
+	err = dir.SearchDeletedFunc(ctx, info.DefaultNamingContext, deletedFilter, []string{"objectGUID"}, func(entry *ldap.Entry) error {
+		guid := objectGUID(entry)
+		if _, ok := updated[guid]; ok {
+			delete(updated, guid)
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err, nil
+	}
+
+	log.Printf("[INFO] LDAP - Incremental%s sejak USN %d: %d berubah, %d dihapus", s.domainLabel(), since, changed, deleted)
+	return &adState{
+		Server:       st.Server,
+		InvocationID: st.InvocationID,
+		HighestUSN:   info.HighestCommittedUSN,
+		LastFullSync: st.LastFullSync,
+		Rows:         updated,
+	}, nil, nil
+}
+
+// deletedObjectsDenied memeriksa apakah DC menolak pencarian Deleted Objects (hak akses atau control
+// show deleted tidak didukung), berbeda dengan gangguan koneksi yang bisa pulih di siklus berikutnya.
+// operationsError tidak termasuk: AD juga mengembalikannya untuk kegagalan sementara (mis. bind hilang).
+func deletedObjectsDenied(err error) bool {
+	return ldap.IsErrorAnyOf(err,
+		ldap.LDAPResultInsufficientAccessRights,
+		ldap.LDAPResultNoSuchObject,
+		ldap.LDAPResultUnavailableCriticalExtension,
+		ldap.LDAPResultUnwillingToPerform,
+	)
+}
+
+// objectGUID mengembalikan objectGUID entry dalam hex, atau DN jika atribut tidak tersedia
+func objectGUID(entry *ldap.Entry) string {
+	if b := entry.GetRawAttributeValue("objectGUID"); len(b) > 0 {
+		return hex.EncodeToString(b)
+	}
+	return strings.ToLower(entry.DN)
+}
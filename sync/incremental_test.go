@@ -0,0 +1,264 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"ocs-ad-inventorymanagement/client"
+	"ocs-ad-inventorymanagement/parser"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+func TestIncrementalAttributesDropLastLogon(t *testing.T) {
+	got := incrementalAttributes([]string{"name", "lastLogon", "lastLogonTimestamp", "LASTLOGON", "uSNChanged"})
+	want := []string{"name", "lastLogonTimestamp", "uSNChanged", "objectGUID"}
+	if !equalStrings(got, want) {
+		t.Errorf("attributes = %v, want %v", got, want)
+	}
+}
+
+// fakeDirectory adalah pengganti adDirectory dengan hasil pencarian statis
+type fakeDirectory struct {
+	info       client.DSAInfo
+	all        []*ldap.Entry // hasil full resync
+	changed    []*ldap.Entry // hasil pencarian uSNChanged
+	deleted    []*ldap.Entry // isi Deleted Objects
+	deletedErr error
+
+	searches []string // filter yang dicari, pencarian Deleted Objects diberi awalan "deleted:"
+}
+
+func (f *fakeDirectory) DSAInfo(context.Context) (client.DSAInfo, error) {
+	return f.info, nil
+}
+
+func (f *fakeDirectory) SearchFunc(_ context.Context, filter string, _ []string, fn func(*ldap.Entry) error) error {
+	f.searches = append(f.searches, filter)
+	entries := f.all
+	if strings.Contains(filter, "uSNChanged>=") {
+		entries = f.changed
+	}
+	for _, e := range entries {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeDirectory) SearchDeletedFunc(_ context.Context, _, filter string, _ []string, fn func(*ldap.Entry) error) error {
+	f.searches = append(f.searches, "deleted:"+filter)
+	if f.deletedErr != nil {
+		return f.deletedErr
+	}
+	for _, e := range f.deleted {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// computerEntry membuat entry komputer dengan objectGUID guid
+func computerEntry(guid, name, os string) *ldap.Entry {
+	return ldap.NewEntry("CN="+name+",OU=PC,DC=corp,DC=local", map[string][]string{
+		"name":            {name},
+		"operatingSystem": {os},
+		"objectGUID":      {guid},
+	})
+}
+
+// guidKey mengembalikan kunci state untuk entry buatan computerEntry
+func guidKey(guid string) string {
+	return objectGUID(computerEntry(guid, "x", ""))
+}
+
+func newIncrementalSource(t *testing.T, st *adState) *LDAPSource {
+	t.Helper()
+	return &LDAPSource{
+		Config: client.LDAPConfig{Incremental: true, FullResyncInterval: 24 * time.Hour, StateDir: t.TempDir()},
+		state:  st,
+	}
+}
+
+// baseState adalah state hasil full resync satu jam lalu dari dc1 pada USN 100
+func baseState() *adState {
+	return &adState{
+		Server:       "dc1",
+		InvocationID: "inv-1",
+		HighestUSN:   100,
+		LastFullSync: time.Now().UTC().Add(-time.Hour),
+		Rows:         map[string]parser.ComputerReportRow{guidKey("a"): {ComputerName: "PC-A"}},
+	}
+}
+
+func rowNames(rows []parser.ComputerReportRow) []string {
+	names := make([]string, 0, len(rows))
+	for _, r := range rows {
+		names = append(names, r.ComputerName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+const (
+	fullFilter    = "(objectClass=computer)"
+	changedFilter = "(&(objectClass=computer)(uSNChanged>=101))"
+	deletedFilter = "deleted:(&(isDeleted=TRUE)(objectClass=computer)(uSNChanged>=101))"
+)
+
+func TestFetchIncrementalFullResyncReasons(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		server string
+		change func(st *adState, info *client.DSAInfo)
+		full   bool
+	}{
+		{name: "tanpa alasan", server: "dc1"},
+		{name: "belum ada state", server: "dc1", full: true},
+		{name: "DC berubah", server: "dc2", full: true},
+		{name: "invocationId berubah", server: "dc1", full: true, change: func(_ *adState, info *client.DSAInfo) { info.InvocationID = "inv-2" }},
+		{name: "interval tercapai", server: "dc1", full: true, change: func(st *adState, _ *client.DSAInfo) { st.LastFullSync = time.Now().Add(-25 * time.Hour) }},
+		{name: "USN mundur", server: "dc1", full: true, change: func(_ *adState, info *client.DSAInfo) { info.HighestCommittedUSN = 50 }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			st := baseState()
+			dir := &fakeDirectory{
+				info: client.DSAInfo{HighestCommittedUSN: 120, InvocationID: "inv-1"},
+				all:  []*ldap.Entry{computerEntry("a", "PC-A", ""), computerEntry("b", "PC-B", "")},
+			}
+			if tc.change != nil {
+				tc.change(st, &dir.info)
+			}
+			if tc.name == "belum ada state" {
+				st = nil
+			}
+			s := newIncrementalSource(t, st)
+
+			rows, err := s.fetchIncremental(context.Background(), dir, tc.server)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tc.full {
+				if !equalStrings(dir.searches, []string{changedFilter, deletedFilter}) {
+					t.Errorf("searches = %v, want incremental", dir.searches)
+				}
+				if got := rowNames(rows); !equalStrings(got, []string{"PC-A"}) {
+					t.Errorf("rows = %v, want [PC-A]", got)
+				}
+				return
+			}
+			if !equalStrings(dir.searches, []string{fullFilter}) {
+				t.Errorf("searches = %v, want full resync", dir.searches)
+			}
+			if got := rowNames(rows); !equalStrings(got, []string{"PC-A", "PC-B"}) {
+				t.Errorf("rows = %v, want [PC-A PC-B]", got)
+			}
+			if s.state.Server != tc.server || s.state.InvocationID != dir.info.InvocationID || s.state.HighestUSN != dir.info.HighestCommittedUSN {
+				t.Errorf("state = %s/%s/%d, want %s/%s/%d", s.state.Server, s.state.InvocationID, s.state.HighestUSN,
+					tc.server, dir.info.InvocationID, dir.info.HighestCommittedUSN)
+			}
+			if time.Since(s.state.LastFullSync) > time.Minute {
+				t.Errorf("LastFullSync = %v, want now", s.state.LastFullSync)
+			}
+		})
+	}
+}
+
+func TestFetchIncrementalDeletedObjectsFallback(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		err        error
+		unreadable bool
+	}{
+		{name: "hak akses ditolak", err: ldap.NewError(ldap.LDAPResultInsufficientAccessRights, errors.New("access denied")), unreadable: true},
+		{name: "control tidak didukung", err: ldap.NewError(ldap.LDAPResultUnavailableCriticalExtension, errors.New("unsupported")), unreadable: true},
+		{name: "operations error sementara", err: ldap.NewError(ldap.LDAPResultOperationsError, errors.New("bind lost"))},
+		{name: "DC sibuk", err: ldap.NewError(ldap.LDAPResultBusy, errors.New("busy"))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := &fakeDirectory{
+				info:       client.DSAInfo{HighestCommittedUSN: 120, InvocationID: "inv-1"},
+				all:        []*ldap.Entry{computerEntry("b", "PC-B", "")},
+				deletedErr: tc.err,
+			}
+			s := newIncrementalSource(t, baseState())
+
+			rows, err := s.fetchIncremental(context.Background(), dir, "dc1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equalStrings(dir.searches, []string{changedFilter, deletedFilter, fullFilter}) {
+				t.Errorf("searches = %v, want incremental lalu full resync", dir.searches)
+			}
+			if got := rowNames(rows); !equalStrings(got, []string{"PC-B"}) {
+				t.Errorf("rows = %v, want hasil full resync [PC-B]", got)
+			}
+			if s.deletedUnreadable != tc.unreadable {
+				t.Errorf("deletedUnreadable = %v, want %v", s.deletedUnreadable, tc.unreadable)
+			}
+
+			// Siklus berikutnya (sejak USN 121 hasil full resync): incremental dilewati
+			// hanya jika Deleted Objects memang ditolak
+			dir.searches = nil
+			if _, err := s.fetchIncremental(context.Background(), dir, "dc1"); err != nil {
+				t.Fatal(err)
+			}
+			want := []string{
+				"(&(objectClass=computer)(uSNChanged>=121))",
+				"deleted:(&(isDeleted=TRUE)(objectClass=computer)(uSNChanged>=121))",
+				fullFilter,
+			}
+			if tc.unreadable {
+				want = []string{fullFilter}
+			}
+			if !equalStrings(dir.searches, want) {
+				t.Errorf("siklus berikutnya searches = %v, want %v", dir.searches, want)
+			}
+		})
+	}
+}
+
+func TestApplyIncrementalMerge(t *testing.T) {
+	st := baseState()
+	st.Rows[guidKey("b")] = parser.ComputerReportRow{ComputerName: "PC-B", OperatingSystem: "Windows 10"}
+	st.Rows[guidKey("c")] = parser.ComputerReportRow{ComputerName: "PC-C"}
+	dir := &fakeDirectory{
+		info: client.DSAInfo{HighestCommittedUSN: 150, InvocationID: "inv-1"},
+		changed: []*ldap.Entry{
+			computerEntry("b", "PC-B", "Windows 11"),
+			computerEntry("d", "PC-D", ""),
+		},
+		// "x" sudah tidak ada di state (mis. dibuat dan dihapus di antara dua siklus)
+		deleted: []*ldap.Entry{computerEntry("c", "PC-C", ""), computerEntry("x", "PC-X", "")},
+	}
+	s := newIncrementalSource(t, st)
+	s.Config.Domain = "corp"
+
+	next, deletedErr, err := s.applyIncremental(context.Background(), dir, st, dir.info, fullFilter, nil)
+	if err != nil || deletedErr != nil {
+		t.Fatalf("err = %v, deletedErr = %v", err, deletedErr)
+	}
+	var rows []parser.ComputerReportRow
+	for _, r := range next.Rows {
+		rows = append(rows, r)
+	}
+	if got := rowNames(rows); !equalStrings(got, []string{"PC-A", "PC-B", "PC-D"}) {
+		t.Errorf("rows = %v, want [PC-A PC-B PC-D]", got)
+	}
+	if b := next.Rows[guidKey("b")]; b.OperatingSystem != "Windows 11" || b.Domain != "corp" {
+		t.Errorf("PC-B = %q/%q, want Windows 11/corp", b.OperatingSystem, b.Domain)
+	}
+	if next.HighestUSN != 150 || next.Server != st.Server || !next.LastFullSync.Equal(st.LastFullSync) {
+		t.Errorf("state = %d/%s/%v, want USN 150 dengan Server dan LastFullSync lama", next.HighestUSN, next.Server, next.LastFullSync)
+	}
+	// State lama tidak boleh ikut berubah, karena masih dipakai jika siklus gagal
+	if len(st.Rows) != 3 || st.Rows[guidKey("b")].OperatingSystem != "Windows 10" {
+		t.Errorf("state lama berubah: %+v", st.Rows)
+	}
+}
@@ -0,0 +1,328 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	gosync "sync"
+	"time"
+)
+
+// Schedule menentukan kapan siklus sinkronisasi berikutnya dijalankan
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// intervalSchedule menjalankan siklus setiap interval tetap
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// ScheduleConfig menyimpan konfigurasi jadwal sinkronisasi
+type ScheduleConfig struct {
+	Interval time.Duration // SYNC_INTERVAL, default 60 detik
+	Cron     string        // SYNC_CRON, jika diisi menggantikan SYNC_INTERVAL
+	Jitter   time.Duration // SYNC_JITTER, jeda acak tambahan 0..Jitter
+
+	// Backoff exponential setelah siklus gagal: BackoffInitial, 2x, 4x, ... hingga BackoffMax
+	BackoffInitial time.Duration // SYNC_BACKOFF_INITIAL, default 10 detik
+	BackoffMax     time.Duration // SYNC_BACKOFF_MAX, default 10 menit
+}
+
+// LoadScheduleConfig memuat konfigurasi jadwal dari environment variables
+func LoadScheduleConfig() ScheduleConfig {
+	return ScheduleConfig{
+		Interval: envDuration("SYNC_INTERVAL", 60*time.Second),
+		Cron:     os.Getenv("SYNC_CRON"),
+		Jitter:   envDuration("SYNC_JITTER", 0),
+
+		BackoffInitial: envDuration("SYNC_BACKOFF_INITIAL", 10*time.Second),
+		BackoffMax:     envDuration("SYNC_BACKOFF_MAX", 10*time.Minute),
+	}
+}
+
+// Schedule membangun Schedule dari konfigurasi
+func (c ScheduleConfig) Schedule() (Schedule, error) {
+	if c.Cron != "" {
+		return ParseCron(c.Cron, time.Local)
+	}
+	if c.Interval <= 0 {
+		return nil, fmt.Errorf("SYNC_INTERVAL harus lebih dari 0")
+	}
+	return intervalSchedule(c.Interval), nil
+}
+
+// Describe mengembalikan deskripsi jadwal untuk keperluan log
+func (c ScheduleConfig) Describe() string {
+	if c.Cron != "" {
+		return fmt.Sprintf("cron %q", c.Cron)
+	}
+	return fmt.Sprintf("setiap %s", c.Interval)
+}
+
+// envDuration membaca durasi Go ("90s", "5m") atau angka detik dari environment
+func envDuration(key string, def time.Duration) time.Duration {
+	s := os.Getenv(key)
+	if s == "" {
+		return def
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return d
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return time.Duration(n) * time.Second
+	}
+	log.Printf("[WARN] Nilai %s=%q tidak valid, memakai default %s", key, s, def)
+	return def
+}
+
+// cycle adalah satu siklus yang sedang/sudah berjalan, dibagi ke semua pemanggil
+type cycle struct {
+	done   chan struct{}
+	report Report
+	err    error
+}
+
+// Status menggambarkan kondisi scheduler, dipakai oleh endpoint status
+type Status struct {
+	State               string     `json:"state"` // "starting", "healthy" atau "degraded"
+	Running             bool       `json:"running"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorStage      string     `json:"last_error_stage,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	LastReport          *Report    `json:"last_report,omitempty"`
+	NextRunAt           *time.Time `json:"next_run_at,omitempty"`
+}
+
+// Scheduler menjalankan Syncer sesuai jadwal dan menerima trigger on-demand.
+// Jadwal yang jatuh saat siklus sedang berjalan digabung ke siklus tersebut, sedangkan
+// trigger on-demand mengantre tepat satu siklus lanjutan yang dibagi ke semua pemanggil.
+// Siklus yang gagal tidak menghentikan proses; siklus berikutnya dijadwalkan
+// dengan backoff exponential dan status berubah menjadi "degraded".
+type Scheduler struct {
+	Syncer   *Syncer
+	Schedule Schedule
+	Jitter   time.Duration
+
+	BackoffInitial time.Duration
+	BackoffMax     time.Duration
+
+	mu      gosync.Mutex
+	current *cycle
+	pending *cycle          // Siklus lanjutan untuk trigger on-demand saat current berjalan
+	ctx     context.Context // context untuk siklus, tidak ikut batal saat sinyal shutdown
+	cancel  context.CancelFunc
+	closed  bool
+	status  Status
+}
+
+// ErrSchedulerClosed dikembalikan RunNow setelah Shutdown dipanggil
+var ErrSchedulerClosed = errors.New("scheduler sinkronisasi sedang berhenti")
+
+// NewScheduler membuat Scheduler baru dari konfigurasi jadwal
+func NewScheduler(s *Syncer, schedule Schedule, cfg ScheduleConfig) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		Syncer:         s,
+		Schedule:       schedule,
+		Jitter:         cfg.Jitter,
+		BackoffInitial: cfg.BackoffInitial,
+		BackoffMax:     cfg.BackoffMax,
+		status:         Status{State: "starting"},
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+// Status mengembalikan salinan status scheduler saat ini
+func (s *Scheduler) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.status
+	st.Running = s.current != nil
+	return st
+}
+
+// backoff menghitung jeda setelah n kegagalan berturut-turut
+func (s *Scheduler) backoff(n int) time.Duration {
+	d := s.BackoffInitial
+	if d <= 0 {
+		d = 10 * time.Second
+	}
+	for i := 1; i < n; i++ {
+		d *= 2
+		if s.BackoffMax > 0 && d >= s.BackoffMax {
+			return s.BackoffMax
+		}
+	}
+	if s.BackoffMax > 0 && d > s.BackoffMax {
+		d = s.BackoffMax
+	}
+	return d
+}
+
+// record memperbarui status setelah satu siklus selesai
+func (s *Scheduler) record(report Report, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.status.LastReport = &report
+	if err == nil {
+		s.status.State = "healthy"
+		s.status.ConsecutiveFailures = 0
+		s.status.LastSuccessAt = &now
+		return
+	}
+	s.status.State = "degraded"
+	s.status.ConsecutiveFailures++
+	s.status.LastError = err.Error()
+	s.status.LastErrorAt = &now
+	s.status.LastErrorStage = ""
+	var stageErr *StageError
+	if errors.As(err, &stageErr) {
+		s.status.LastErrorStage = stageErr.Stage
+	}
+}
+
+// Run menjalankan siklus pertama segera, lalu mengikuti jadwal sampai ctx dibatalkan.
+// Siklus yang sedang berjalan saat ctx dibatalkan tidak ikut dibatalkan;
+// gunakan Shutdown untuk menunggu atau menghentikannya.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		c := s.start(false)
+		if c == nil {
+			return
+		}
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return
+		}
+
+		var wait time.Duration
+		if failures := s.Status().ConsecutiveFailures; failures > 0 {
+			wait = s.backoff(failures)
+			log.Printf("[WARN] Siklus gagal %d kali berturut-turut, mencoba lagi dalam %s", failures, wait)
+		} else {
+			next := s.Schedule.Next(time.Now())
+			if next.IsZero() {
+				log.Println("[ERROR] Jadwal sinkronisasi tidak menghasilkan waktu berikutnya, scheduler berhenti")
+				return
+			}
+			wait = time.Until(next)
+		}
+		if s.Jitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(s.Jitter)))
+		}
+		nextRun := time.Now().Add(wait)
+		s.mu.Lock()
+		s.status.NextRunAt = &nextRun
+		s.mu.Unlock()
+		log.Printf("----------------- Siklus Selesai, Menunggu %s -----------------", wait.Round(time.Second))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// RunNow memicu siklus segera dan mengembalikan report-nya. Jika siklus sedang berjalan,
+// satu siklus lanjutan dijalankan setelahnya agar report mencerminkan data terbaru
+// (mis. setelah komputer dihapus); trigger lain pada saat itu berbagi siklus lanjutan yang sama.
+func (s *Scheduler) RunNow(ctx context.Context) (Report, error) {
+	c := s.start(true)
+	if c == nil {
+		return Report{}, ErrSchedulerClosed
+	}
+	select {
+	case <-c.done:
+		return c.report, c.err
+	case <-ctx.Done():
+		return Report{}, ctx.Err()
+	}
+}
+
+// Shutdown menolak trigger baru dan menunggu siklus yang sedang berjalan selesai.
+// Jika ctx habis lebih dulu, siklus dibatalkan dan Shutdown menunggu siklus tersebut berhenti.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	c := s.current
+	s.mu.Unlock()
+	defer s.cancel()
+
+	if c == nil {
+		return nil
+	}
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		log.Println("[WARN] Batas waktu shutdown tercapai, membatalkan siklus sinkronisasi yang sedang berjalan")
+		s.cancel()
+		<-c.done
+		return ctx.Err()
+	}
+}
+
+// start memulai siklus baru jika belum ada yang berjalan. Jika ada, siklus tersebut
+// dikembalikan, atau dengan fresh siklus lanjutan yang dijalankan setelahnya.
+// Mengembalikan nil jika scheduler sudah di-shutdown.
+func (s *Scheduler) start(fresh bool) *cycle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	if s.current != nil {
+		if !fresh {
+			return s.current
+		}
+		if s.pending == nil {
+			s.pending = &cycle{done: make(chan struct{})}
+		}
+		return s.pending
+	}
+	c := &cycle{done: make(chan struct{})}
+	s.launch(c)
+	return c
+}
+
+// launch menjalankan c sebagai siklus aktif; s.mu harus sudah dikunci
+func (s *Scheduler) launch(c *cycle) {
+	ctx := s.ctx
+	s.current = c
+
+	go func() {
+		c.report, c.err = s.Syncer.RunOnce(ctx)
+		if c.err != nil {
+			log.Printf("[ERROR] Siklus sinkronisasi gagal: %v", c.err)
+		}
+		s.record(c.report, c.err)
+		s.mu.Lock()
+		s.current = nil
+		if next := s.pending; next != nil {
+			s.pending = nil
+			if s.closed {
+				next.err = ErrSchedulerClosed
+				close(next.done)
+			} else {
+				s.launch(next)
+			}
+		}
+		s.mu.Unlock()
+		close(c.done)
+	}()
+}
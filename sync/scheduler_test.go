@@ -0,0 +1,213 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"ocs-ad-inventorymanagement/parser"
+)
+
+// gatedAD menahan setiap ListComputers hingga release dipanggil, dan menghitung jumlah siklus
+type gatedAD struct {
+	mu      gosync.Mutex
+	calls   int
+	err     error
+	started chan struct{}
+	gate    chan struct{}
+}
+
+func newGatedAD() *gatedAD {
+	return &gatedAD{started: make(chan struct{}, 10), gate: make(chan struct{})}
+}
+
+func (g *gatedAD) ListComputers(ctx context.Context) ([]parser.ComputerReportRow, error) {
+	g.mu.Lock()
+	g.calls++
+	err := g.err
+	g.mu.Unlock()
+	g.started <- struct{}{}
+	select {
+	case <-g.gate:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return nil, err
+}
+
+func (g *gatedAD) release() {
+	g.gate <- struct{}{}
+}
+
+func (g *gatedAD) callCount() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.calls
+}
+
+// waitStarted menunggu satu siklus mulai memanggil sumber AD
+func waitStarted(t *testing.T, g *gatedAD) {
+	t.Helper()
+	select {
+	case <-g.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("siklus tidak dimulai")
+	}
+}
+
+func newTestScheduler(ad ADSource) *Scheduler {
+	syncer := NewSyncer(ad, &fakeOCS{}, newFakeSink())
+	return NewScheduler(syncer, intervalSchedule(time.Hour), ScheduleConfig{})
+}
+
+func TestSchedulerCoalescesScheduledRuns(t *testing.T) {
+	ad := newGatedAD()
+	s := newTestScheduler(ad)
+
+	c1 := s.start(false)
+	waitStarted(t, ad)
+	// Jadwal yang jatuh saat siklus berjalan memakai siklus yang sama
+	if c2 := s.start(false); c2 != c1 {
+		t.Fatal("start(false) saat siklus berjalan harus mengembalikan siklus yang sama")
+	}
+	if !s.Status().Running {
+		t.Error("Status().Running = false saat siklus berjalan")
+	}
+	ad.release()
+	<-c1.done
+	if n := ad.callCount(); n != 1 {
+		t.Errorf("siklus dijalankan %d kali, want 1", n)
+	}
+}
+
+func TestSchedulerRunNowQueuesOneFollowUp(t *testing.T) {
+	ad := newGatedAD()
+	s := newTestScheduler(ad)
+	first := s.start(false)
+	waitStarted(t, ad)
+
+	// Dua trigger on-demand saat siklus berjalan berbagi satu siklus lanjutan
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := s.RunNow(context.Background())
+			results <- err
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		queued := s.pending != nil
+		s.mu.Unlock()
+		if queued {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("siklus lanjutan tidak diantre")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond) // beri waktu trigger kedua bergabung
+
+	ad.release()
+	<-first.done
+	select {
+	case err := <-results:
+		t.Fatalf("RunNow selesai bersama siklus lama (err=%v), want menunggu siklus lanjutan", err)
+	default:
+	}
+
+	waitStarted(t, ad)
+	ad.release()
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := ad.callCount(); n != 2 {
+		t.Errorf("siklus dijalankan %d kali, want 2 (awal + satu lanjutan)", n)
+	}
+}
+
+func TestSchedulerShutdownRejectsPendingRun(t *testing.T) {
+	ad := newGatedAD()
+	s := newTestScheduler(ad)
+	first := s.start(false)
+	waitStarted(t, ad)
+	pending := s.start(true)
+
+	done := make(chan error, 1)
+	go func() { done <- s.Shutdown(context.Background()) }()
+	for closed := false; !closed; {
+		time.Sleep(time.Millisecond)
+		s.mu.Lock()
+		closed = s.closed
+		s.mu.Unlock()
+	}
+	ad.release()
+	<-first.done
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	<-pending.done
+	if !errors.Is(pending.err, ErrSchedulerClosed) {
+		t.Errorf("siklus lanjutan err = %v, want ErrSchedulerClosed", pending.err)
+	}
+	if _, err := s.RunNow(context.Background()); !errors.Is(err, ErrSchedulerClosed) {
+		t.Errorf("RunNow setelah Shutdown err = %v, want ErrSchedulerClosed", err)
+	}
+}
+
+func TestSchedulerRecordsFailures(t *testing.T) {
+	ad := newGatedAD()
+	ad.err = errors.New("ldap down")
+	s := newTestScheduler(ad)
+
+	for i := 1; i <= 2; i++ {
+		c := s.start(false)
+		waitStarted(t, ad)
+		ad.release()
+		<-c.done
+		st := s.Status()
+		if st.State != "degraded" || st.ConsecutiveFailures != i || st.LastErrorStage != StageAD {
+			t.Fatalf("siklus %d: status = %+v, want degraded, %d kegagalan, tahap ad", i, st, i)
+		}
+	}
+
+	ad.mu.Lock()
+	ad.err = nil
+	ad.mu.Unlock()
+	c := s.start(false)
+	waitStarted(t, ad)
+	ad.release()
+	<-c.done
+	if st := s.Status(); st.State != "healthy" || st.ConsecutiveFailures != 0 || st.LastSuccessAt == nil {
+		t.Errorf("status = %+v, want healthy setelah siklus berhasil", st)
+	}
+}
+
+func TestSchedulerBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		initial  time.Duration
+		max      time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{name: "kegagalan pertama", initial: 10 * time.Second, max: 10 * time.Minute, failures: 1, want: 10 * time.Second},
+		{name: "berlipat dua", initial: 10 * time.Second, max: 10 * time.Minute, failures: 4, want: 80 * time.Second},
+		{name: "dibatasi maksimum", initial: 10 * time.Second, max: 10 * time.Minute, failures: 7, want: 10 * time.Minute},
+		{name: "kegagalan sangat banyak", initial: 10 * time.Second, max: 10 * time.Minute, failures: 1000, want: 10 * time.Minute},
+		{name: "initial melebihi maksimum", initial: time.Hour, max: time.Minute, failures: 1, want: time.Minute},
+		{name: "initial default", failures: 2, want: 20 * time.Second},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &Scheduler{BackoffInitial: tc.initial, BackoffMax: tc.max}
+			if got := s.backoff(tc.failures); got != tc.want {
+				t.Errorf("backoff(%d) = %s, want %s", tc.failures, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"ocs-ad-inventorymanagement/client"
+)
+
+// SnapshotSink adalah sink yang membangun setiap siklus sebagai snapshot penuh
+// di lokasi baru, lalu mengaktifkannya sekaligus saat siklus selesai tanpa error.
+type SnapshotSink interface {
+	Sink
+	// BeginSnapshot menyiapkan lokasi snapshot baru dan mengembalikan namanya
+	BeginSnapshot(ctx context.Context) (string, error)
+	// CommitSnapshot mengaktifkan snapshot yang sedang dibangun
+	CommitSnapshot(ctx context.Context) error
+	// AbortSnapshot membuang snapshot yang sedang dibangun
+	AbortSnapshot(ctx context.Context) error
+}
+
+// VersionedElasticsearchSink menulis setiap siklus ke index berversi baru
+// (mis. "inventory-20261017T120000") lalu memindahkan alias ELASTICSEARCH_INDEX_NAME ke index tersebut.
+// Index lama disimpan sebanyak Retain generasi untuk rollback.
+type VersionedElasticsearchSink struct {
+	*ElasticsearchSink
+	Retain int
+
+	building string
+}
+
+// NewVersionedElasticsearchSink membuat sink berversi di atas base; base.Index dipakai sebagai nama alias
+func NewVersionedElasticsearchSink(base *ElasticsearchSink) *VersionedElasticsearchSink {
+	return &VersionedElasticsearchSink{
+		ElasticsearchSink: base,
+		Retain:            base.Client.Config.RetainGenerations,
+	}
+}
+
+// BeginSnapshot membuat index berversi baru sebagai target Bulk
+func (s *VersionedElasticsearchSink) BeginSnapshot(ctx context.Context) (string, error) {
+	if !s.templateReady {
+		// Template harus ada sebelum index dibuat agar mapping-nya benar
+		if err := s.EnsureMapping(ctx); err != nil {
+			return "", err
+		}
+	}
+	index := s.Index + "-" + time.Now().UTC().Format(client.SnapshotIndexLayout)
+	if err := s.Client.CreateIndex(ctx, index); err != nil {
+		return "", err
+	}
+	s.building = index
+	return index, nil
+}
+
+// Bulk mengirim operasi ke index yang sedang dibangun
+func (s *VersionedElasticsearchSink) Bulk(ctx context.Context, ops []client.BulkOperation) (client.BulkResult, error) {
+	if s.building == "" {
+		return client.BulkResult{}, fmt.Errorf("snapshot belum dimulai")
+	}
+	return s.Client.Bulk(ctx, s.building, ops)
+}
+
+// CommitSnapshot memindahkan alias ke index baru secara atomik, lalu menghapus generasi lama
+func (s *VersionedElasticsearchSink) CommitSnapshot(ctx context.Context) error {
+	if s.building == "" {
+		return fmt.Errorf("snapshot belum dimulai")
+	}
+	index := s.building
+
+	// Index konkret bernama sama dengan alias (mode non-versi sebelumnya) dihapus
+	// dalam aksi yang sama dengan penambahan alias.
+	var legacy []string
+	exists, err := s.Client.IndexExists(ctx, s.Index)
+	if err != nil {
+		return err
+	}
+	if exists {
+		log.Printf("[WARN] Elasticsearch - Index lama %s diganti dengan alias ke %s", s.Index, index)
+		legacy = append(legacy, s.Index)
+	}
+
+	aliases := append([]client.Alias{{Name: s.Index}}, s.views()...)
+	if err := s.Client.SwapAlias(ctx, aliases, s.Index+"-*", index, legacy); err != nil {
+		return err
+	}
+	s.building = ""
+	log.Printf("[SUCCESS] Elasticsearch - Alias %s sekarang menunjuk ke %s", s.Index, index)
+
+	if err := s.pruneGenerations(ctx, index); err != nil {
+		// Alias sudah aktif, kegagalan membersihkan index lama tidak menggagalkan siklus
+		log.Printf("[ERROR] Elasticsearch - Gagal menghapus generasi index lama: %v", err)
+	}
+	return nil
+}
+
+// AbortSnapshot menghapus index yang gagal dibangun; alias tetap menunjuk ke snapshot sebelumnya
+func (s *VersionedElasticsearchSink) AbortSnapshot(ctx context.Context) error {
+	if s.building == "" {
+		return nil
+	}
+	index := s.building
+	s.building = ""
+	log.Printf("[WARN] Elasticsearch - Snapshot %s dibatalkan, alias %s tidak diubah", index, s.Index)
+	return s.Client.DeleteIndex(ctx, index)
+}
+
+// pruneGenerations menyisakan index aktif ditambah Retain generasi sebelumnya
+func (s *VersionedElasticsearchSink) pruneGenerations(ctx context.Context, active string) error {
+	indices, err := s.Client.SnapshotIndices(ctx, s.Index)
+	if err != nil {
+		return err
+	}
+	// indices terurut dari yang tertua; index lebih baru dari active (tidak seharusnya ada) tidak disentuh
+	var older []string
+	for _, idx := range indices {
+		if idx < active {
+			older = append(older, idx)
+		}
+	}
+	// Retain negatif diperlakukan sebagai 0: hanya index aktif yang tersisa
+	for len(older) > max(s.Retain, 0) {
+		if err := s.Client.DeleteIndex(ctx, older[0]); err != nil {
+			return err
+		}
+		log.Printf("[INFO] Elasticsearch - Generasi index lama %s dihapus", older[0])
+		older = older[1:]
+	}
+	return nil
+}
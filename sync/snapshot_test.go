@@ -0,0 +1,99 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	gosync "sync"
+	"testing"
+
+	"ocs-ad-inventorymanagement/client"
+)
+
+// fakeIndices adalah pengganti lokal endpoint daftar dan hapus index Elasticsearch
+type fakeIndices struct {
+	mu      gosync.Mutex
+	indices map[string]bool
+	deleted []string
+}
+
+func (f *fakeIndices) handle(w http.ResponseWriter, r *http.Request) {
+	// Client v8 memeriksa header ini untuk memastikan server adalah Elasticsearch
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	switch r.Method {
+	case http.MethodGet:
+		prefix := strings.TrimSuffix(name, "*")
+		resp := make(map[string]interface{})
+		for idx := range f.indices {
+			if strings.HasPrefix(idx, prefix) {
+				resp[idx] = map[string]interface{}{}
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	case http.MethodDelete:
+		delete(f.indices, name)
+		f.deleted = append(f.deleted, name)
+		w.Write([]byte(`{"acknowledged":true}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestPruneGenerations(t *testing.T) {
+	generations := []string{
+		"inventory-20261001T000000",
+		"inventory-20261002T000000",
+		"inventory-20261003T000000",
+		"inventory-20261004T000000",
+	}
+	active := "inventory-20261004T000000"
+
+	for _, tc := range []struct {
+		name        string
+		retain      int
+		wantDeleted []string
+	}{
+		{name: "simpan dua", retain: 2, wantDeleted: []string{"inventory-20261001T000000"}},
+		{name: "simpan lebih dari yang ada", retain: 5},
+		{name: "hanya index aktif", retain: 0, wantDeleted: generations[:3]},
+		{name: "negatif seperti nol", retain: -1, wantDeleted: generations[:3]},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := &fakeIndices{indices: map[string]bool{
+				// Bukan generasi milik alias, tidak boleh disentuh
+				"inventory-ocs-duplicates": true,
+				// Lebih baru dari index aktif, tidak boleh disentuh
+				"inventory-20261005T000000": true,
+			}}
+			for _, idx := range generations {
+				f.indices[idx] = true
+			}
+			srv := httptest.NewServer(http.HandlerFunc(f.handle))
+			defer srv.Close()
+			c, err := client.NewElasticsearchClient(client.ElasticsearchConfig{URL: srv.URL, Index: "inventory"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := &VersionedElasticsearchSink{ElasticsearchSink: NewElasticsearchSink(c), Retain: tc.retain}
+
+			if err := s.pruneGenerations(context.Background(), active); err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(f.deleted)
+			if !equalStrings(f.deleted, tc.wantDeleted) {
+				t.Errorf("deleted = %v, want %v", f.deleted, tc.wantDeleted)
+			}
+			if !f.indices[active] || !f.indices["inventory-20261005T000000"] || !f.indices["inventory-ocs-duplicates"] {
+				t.Errorf("index yang seharusnya tersisa ikut terhapus: %v", f.indices)
+			}
+		})
+	}
+}
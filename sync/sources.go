@@ -0,0 +1,337 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ocs-ad-inventorymanagement/client"
+	"ocs-ad-inventorymanagement/parser"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/go-ldap/ldap/v3"
+	"gorm.io/gorm"
+)
+
+// LDAPSource mengambil komputer dari Active Directory lewat LDAPClient.
+// Koneksi dibuat ulang secara otomatis jika belum ada atau pencarian gagal.
+type LDAPSource struct {
+	Config client.LDAPConfig
+	Client *client.LDAPClient
+
+	state             *adState // High-water mark mode incremental
+	deletedUnreadable bool     // Deleted Objects tidak bisa dibaca akun bind, incremental dinonaktifkan hingga restart
+}
+
+// NewLDAPSource membuat LDAPSource; c boleh nil jika koneksi awal gagal
+func NewLDAPSource(cfg client.LDAPConfig, c *client.LDAPClient) *LDAPSource {
+	return &LDAPSource{Config: cfg, Client: c}
+}
+
+// Connect membuat koneksi LDAP baru, menutup koneksi lama jika ada
+func (s *LDAPSource) Connect() error {
+	s.Close()
+	c, err := client.NewLDAPClient(s.Config)
+	if err != nil {
+		return err
+	}
+	s.Client = c
+	return nil
+}
+
+// ListComputers mengambil dan mem-parsing data komputer dari AD secara streaming per halaman.
+// Jika pencarian gagal, koneksi dibuat ulang ke DC berikutnya hingga semua DC dicoba.
+func (s *LDAPSource) ListComputers(ctx context.Context) ([]parser.ComputerReportRow, error) {
+	attempts := 2
+	if servers, err := s.Config.Servers(); err == nil && len(servers) > attempts {
+		attempts = len(servers)
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if s.Client == nil || attempt > 1 {
+			if err := s.Connect(); err != nil {
+				// Semua DC sudah dicoba oleh NewLDAPClient
+				return nil, fmt.Errorf("gagal koneksi ke LDAP: %v", err)
+			}
+		}
+
+		rows, err := s.fetch(ctx)
+		if err == nil {
+			log.Printf("[INFO] LDAP - Data%s diambil dari domain controller %s", s.domainLabel(), s.Client.Server)
+			if s.Config.LastLogonAllDCs {
+				s.applyLastLogonFromAllDCs(ctx, rows)
+			}
+			return rows, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+		// Coba re-koneksi (round-robin ke DC berikutnya) sebelum gagal
+		log.Printf("[ERROR] Gagal mengambil data dari LDAP %s: %v. Mencoba re-koneksi...", s.Client.Server, err)
+	}
+	return nil, fmt.Errorf("gagal mengambil data dari LDAP setelah %d percobaan: %v", attempts, lastErr)
+}
+
+// fetch mengambil populasi komputer lengkap, secara penuh atau incremental
+func (s *LDAPSource) fetch(ctx context.Context) ([]parser.ComputerReportRow, error) {
+	if s.Config.Incremental {
+		return s.fetchIncremental(ctx, s.Client, s.Client.Server.Host)
+	}
+	var rows []parser.ComputerReportRow
+	err := s.Client.ListComputersFunc(ctx, func(entry *ldap.Entry) error {
+		if row, ok := parser.ParseComputerEntry(entry); ok {
+			row.Domain = s.Config.Domain
+			rows = append(rows, row)
+		}
+		return nil
+	})
+	return rows, err
+}
+
+// applyLastLogonFromAllDCs mengganti lastLogon setiap baris dengan nilai terbaru dari semua DC.
+// Jika query gagal, nilai dari DC utama (atau lastLogonTimestamp) tetap dipakai.
+func (s *LDAPSource) applyLastLogonFromAllDCs(ctx context.Context, rows []parser.ComputerReportRow) {
+	lastLogons, err := s.Client.LastLogonFromAllDCs(ctx)
+	if err != nil {
+		log.Printf("[WARN] LDAP - lastLogon semua DC tidak tersedia, memakai nilai DC utama: %v", err)
+		return
+	}
+	for i := range rows {
+		if ll, ok := lastLogons[strings.ToLower(rows[i].DistinguishedName)]; ok {
+			rows[i].SetLastLogon(ll.Value, ll.Server)
+		}
+	}
+}
+
+// domainLabel mengembalikan label domain untuk pesan log
+func (s *LDAPSource) domainLabel() string {
+	if s.Config.Domain == "" {
+		return ""
+	}
+	return " domain " + s.Config.Domain
+}
+
+// Close menutup koneksi LDAP yang sedang dipakai
+func (s *LDAPSource) Close() {
+	if s.Client != nil {
+		s.Client.Close()
+		s.Client = nil
+	}
+}
+
+// MultiADSource menggabungkan beberapa sumber AD (multi-domain/multi-forest).
+// Jika salah satu sumber gagal, seluruh siklus gagal agar data domain tersebut tidak ikut terhapus.
+type MultiADSource []*LDAPSource
+
+// NewMultiADSource membuat sumber AD dari beberapa konfigurasi LDAP
+func NewMultiADSource(configs []client.LDAPConfig) MultiADSource {
+	var sources MultiADSource
+	for _, cfg := range configs {
+		sources = append(sources, NewLDAPSource(cfg, nil))
+	}
+	return sources
+}
+
+// ListComputers mengambil komputer dari semua sumber AD
+func (m MultiADSource) ListComputers(ctx context.Context) ([]parser.ComputerReportRow, error) {
+	var rows []parser.ComputerReportRow
+	for _, src := range m {
+		r, err := src.ListComputers(ctx)
+		if err != nil {
+			if src.Config.Domain != "" {
+				return nil, fmt.Errorf("domain %s: %v", src.Config.Domain, err)
+			}
+			return nil, err
+		}
+		rows = append(rows, r...)
+	}
+	return rows, nil
+}
+
+// Close menutup semua koneksi LDAP
+func (m MultiADSource) Close() {
+	for _, src := range m {
+		src.Close()
+	}
+}
+
+// OCSDBSource mengambil komputer dari database OCS
+type OCSDBSource struct {
+	DB       *gorm.DB
+	PageSize int // Jumlah baris hardware per query, <= 0 memakai parser.DefaultOCSPageSize
+}
+
+// ListComputers mengambil seluruh data komputer dari tabel hardware OCS
+func (s *OCSDBSource) ListComputers(ctx context.Context) ([]parser.OCSComputerRow, error) {
+	return parser.ListOCSComputers(s.DB.WithContext(ctx), s.PageSize)
+}
+
+// ListComputersFunc mengalirkan data komputer OCS per halaman ke fn
+func (s *OCSDBSource) ListComputersFunc(ctx context.Context, fn func(parser.OCSComputerRow) error) error {
+	return parser.ListOCSComputersFunc(s.DB.WithContext(ctx), s.PageSize, fn)
+}
+
+// ElasticsearchSink menyimpan hasil gabungan ke satu index Elasticsearch
+type ElasticsearchSink struct {
+	Client *client.ElasticsearchClient
+	Index  string
+
+	templateReady bool
+	viewsReady    bool
+}
+
+// views mengembalikan alias berfilter yang dipasang di atas index inventaris
+func (s *ElasticsearchSink) views() []client.Alias {
+	return []client.Alias{{
+		// Komputer dengan lebih dari satu baris hardware di OCS
+		Name: s.Index + "-ocs-duplicates",
+		Filter: map[string]interface{}{
+			"range": map[string]interface{}{"ocs_duplicate_count": map[string]interface{}{"gt": 1}},
+		},
+	}}
+}
+
+// NewElasticsearchSink membuat sink untuk index yang dikonfigurasi di client
+func NewElasticsearchSink(c *client.ElasticsearchClient) *ElasticsearchSink {
+	return &ElasticsearchSink{Client: c, Index: c.Config.Index}
+}
+
+// EnsureMapping memasang index template dengan mapping eksplisit untuk index sink,
+// lalu memeriksa apakah index yang sudah ada memakai tipe field yang berbeda.
+// Konflik hanya dilaporkan: index lama perlu di-reindex agar mapping baru berlaku.
+func (s *ElasticsearchSink) EnsureMapping(ctx context.Context) error {
+	if s.Index == "" {
+		return fmt.Errorf("nama index Elasticsearch belum dikonfigurasi")
+	}
+	patterns := []string{s.Index, s.Index + "-*"}
+	tmpl := client.IndexTemplate{Patterns: patterns, Properties: parser.ComputerIndexProperties}
+	if err := s.Client.PutIndexTemplate(ctx, s.Index, tmpl); err != nil {
+		return err
+	}
+	s.templateReady = true
+	log.Printf("[SUCCESS] Elasticsearch - Index template %s terpasang untuk %v", s.Index, patterns)
+
+	conflicts, err := s.Client.IndexMappingConflicts(ctx, s.Index, parser.ComputerIndexProperties)
+	if err != nil {
+		return err
+	}
+	for _, c := range conflicts {
+		log.Printf("[WARN] Elasticsearch - Mapping tidak sesuai template, reindex diperlukan: %s", c)
+	}
+	return nil
+}
+
+// documentFields adalah field _source yang dibutuhkan untuk deteksi perubahan
+var documentFields = []string{
+	"computer_name", "ad_domain", "content_hash",
+	"exists_in_ad", "exists_in_ocs", "ad_status", "ocs_status",
+	"staleness", "lifecycle_state",
+}
+
+// ListDocuments mengambil semua dokumen di index (hanya documentFields), dikelompokkan per document ID.
+// Memakai scroll sehingga tidak dibatasi max_result_window. Index yang belum ada menghasilkan map kosong.
+func (s *ElasticsearchSink) ListDocuments(ctx context.Context) (map[string]parser.FinalComputerRow, error) {
+	es := s.Client.Client
+	query, err := json.Marshal(map[string]interface{}{
+		"query":   map[string]interface{}{"match_all": map[string]interface{}{}},
+		"_source": documentFields,
+		"size":    5000,
+		"sort":    []string{"_doc"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type scrollPage struct {
+		ScrollID string `json:"_scroll_id"`
+		Hits     struct {
+			Hits []struct {
+				ID     string                  `json:"_id"`
+				Source parser.FinalComputerRow `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	decode := func(res *esapi.Response, err error) (scrollPage, bool, error) {
+		var page scrollPage
+		if err != nil {
+			return page, false, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode == http.StatusNotFound {
+			return page, false, nil
+		}
+		if res.IsError() {
+			return page, false, fmt.Errorf("pencarian Elasticsearch gagal: %s", res.Status())
+		}
+		if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+			return page, false, fmt.Errorf("gagal decode response Elasticsearch: %v", err)
+		}
+		return page, true, nil
+	}
+
+	docs := make(map[string]parser.FinalComputerRow)
+	page, ok, err := decode(es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(s.Index),
+		es.Search.WithBody(bytes.NewReader(query)),
+		es.Search.WithScroll(time.Minute),
+	))
+	if err != nil || !ok {
+		return docs, err
+	}
+	defer func() {
+		if page.ScrollID == "" {
+			return
+		}
+		res, err := es.ClearScroll(es.ClearScroll.WithScrollID(page.ScrollID))
+		if err == nil {
+			res.Body.Close()
+		}
+	}()
+
+	for len(page.Hits.Hits) > 0 {
+		for _, hit := range page.Hits.Hits {
+			docs[hit.ID] = hit.Source
+		}
+		next, ok, err := decode(es.Scroll(
+			es.Scroll.WithContext(ctx),
+			es.Scroll.WithScrollID(page.ScrollID),
+			es.Scroll.WithScroll(time.Minute),
+		))
+		if err != nil {
+			return docs, err
+		}
+		if !ok {
+			break
+		}
+		page = next
+	}
+	return docs, nil
+}
+
+// Bulk mengirim operasi index/delete ke index sink
+func (s *ElasticsearchSink) Bulk(ctx context.Context, ops []client.BulkOperation) (client.BulkResult, error) {
+	// Pastikan template sudah terpasang sebelum index dibuat otomatis oleh Bulk API
+	if !s.templateReady {
+		if err := s.EnsureMapping(ctx); err != nil {
+			log.Printf("[ERROR] Elasticsearch - Gagal memasang index template: %v", err)
+		}
+	}
+	res, err := s.Client.Bulk(ctx, s.Index, ops)
+	// Alias view hanya bisa dipasang setelah index ada
+	if err == nil && !s.viewsReady && res.Indexed > 0 {
+		if err := s.Client.PutAliases(ctx, s.Index, s.views()); err != nil {
+			log.Printf("[ERROR] Elasticsearch - Gagal memasang alias view: %v", err)
+		} else {
+			s.viewsReady = true
+		}
+	}
+	return res, err
+}
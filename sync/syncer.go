@@ -0,0 +1,253 @@
+// Package sync berisi satu siklus sinkronisasi AD x OCS -> Elasticsearch
+// yang dapat dijalankan oleh scheduler maupun dipanggil secara on-demand.
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"ocs-ad-inventorymanagement/client"
+	"ocs-ad-inventorymanagement/parser"
+)
+
+// ADSource adalah sumber data komputer dari Active Directory
+type ADSource interface {
+	ListComputers(ctx context.Context) ([]parser.ComputerReportRow, error)
+}
+
+// OCSSource adalah sumber data komputer dari OCS Inventory.
+// Data dialirkan per baris agar tidak perlu ditampung seluruhnya di memori.
+type OCSSource interface {
+	ListComputersFunc(ctx context.Context, fn func(parser.OCSComputerRow) error) error
+}
+
+// Sink adalah tujuan penyimpanan hasil gabungan (Elasticsearch)
+type Sink interface {
+	// ListDocuments mengembalikan dokumen yang tersimpan (minimal field status dan content_hash) per document ID
+	ListDocuments(ctx context.Context) (map[string]parser.FinalComputerRow, error)
+	Bulk(ctx context.Context, ops []client.BulkOperation) (client.BulkResult, error)
+}
+
+// Notifier mengevaluasi aturan notifikasi terhadap hasil gabungan
+type Notifier interface {
+	Notify(ctx context.Context, rows []parser.FinalComputerRow) error
+}
+
+// Report merangkum hasil satu siklus sinkronisasi
+type Report struct {
+	StartedAt     time.Time             `json:"started_at"`
+	Duration      time.Duration         `json:"duration"`
+	ADFetched     int                   `json:"ad_fetched"`
+	OCSFetched    int                   `json:"ocs_fetched"`
+	OCSDuplicates []parser.OCSDuplicate `json:"ocs_duplicates,omitempty"` // Nama komputer dengan lebih dari satu baris hardware
+	Merged        int                   `json:"merged"`
+	Indexed       int                   `json:"indexed"`
+	Deleted       int                   `json:"deleted"`
+	Failed        int                   `json:"failed"`
+	Index         string                `json:"index,omitempty"`   // Index berversi yang dibangun (mode snapshot)
+	History       int                   `json:"history,omitempty"` // Dokumen riwayat harian yang ditulis
+	Unchanged     int                   `json:"unchanged"`         // Dokumen yang dilewati karena tidak berubah
+	Changes       int                   `json:"changes"`           // Jumlah ChangeEvent yang dihasilkan
+}
+
+// Tahapan siklus, dipakai di StageError
+const (
+	StageAD   = "ad"
+	StageOCS  = "ocs"
+	StageSink = "elasticsearch"
+)
+
+// StageError menandai tahapan siklus yang gagal
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("tahap %s gagal: %v", e.Stage, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Syncer menjalankan siklus ambil AD, ambil OCS, gabungkan, lalu simpan ke sink
+type Syncer struct {
+	AD   ADSource
+	OCS  OCSSource
+	Sink Sink
+
+	// History opsional, menerima snapshot harian setelah sink berhasil diperbarui
+	History History
+	// Events opsional, menerima daftar perubahan inventaris setiap siklus
+	Events EventSink
+	// Notifier opsional, dievaluasi setelah data OCS dan AD digabungkan
+	Notifier Notifier
+}
+
+// NewSyncer membuat Syncer baru dari sumber AD, OCS dan sink
+func NewSyncer(ad ADSource, ocs OCSSource, sink Sink) *Syncer {
+	return &Syncer{AD: ad, OCS: ocs, Sink: sink}
+}
+
+// RunOnce menjalankan satu siklus sinkronisasi lengkap
+func (s *Syncer) RunOnce(ctx context.Context) (Report, error) {
+	report := Report{StartedAt: time.Now()}
+	err := s.run(ctx, &report)
+	report.Duration = time.Since(report.StartedAt)
+	return report, err
+}
+
+func (s *Syncer) run(ctx context.Context, report *Report) error {
+	// --- Ambil data dari AD ---
+	adList, err := s.AD.ListComputers(ctx)
+	if err != nil {
+		return &StageError{Stage: StageAD, Err: err}
+	}
+	report.ADFetched = len(adList)
+	log.Printf("[SUCCESS] LDAP - Data berhasil diparsing, Total: %d", len(adList))
+
+	// --- Ambil data dari OCS, langsung dialirkan ke penggabung ---
+	combiner := parser.NewCombiner()
+	err = s.OCS.ListComputersFunc(ctx, func(row parser.OCSComputerRow) error {
+		combiner.AddOCS(row)
+		report.OCSFetched++
+		return nil
+	})
+	if err != nil {
+		return &StageError{Stage: StageOCS, Err: err}
+	}
+	log.Printf("[SUCCESS] OCS - Data berhasil diparsing, Total: %d", report.OCSFetched)
+
+	// --- Gabungkan data OCS dan AD ---
+	for _, ad := range adList {
+		combiner.AddAD(ad)
+	}
+	finalList := combiner.Rows()
+	report.OCSDuplicates = combiner.Duplicates()
+	if n := len(report.OCSDuplicates); n > 0 {
+		// Detail tersedia di /sync/status dan alias view <index>-ocs-duplicates
+		log.Printf("[WARN] OCS - %d nama komputer memiliki lebih dari satu baris hardware, dipakai baris dengan LASTCOME terbaru", n)
+	}
+	report.Merged = len(finalList)
+	log.Printf("[SUCCESS] OCS x AD - Data digabungkan, Total: %d", len(finalList))
+
+	// Dokumen yang tersimpan saat ini, untuk ID dokumen, pruning dan deteksi perubahan
+	prev, err := s.Sink.ListDocuments(ctx)
+	if err != nil {
+		// Lanjutkan indexing walaupun pruning dan deteksi perubahan tidak bisa dilakukan
+		log.Printf("[ERROR] Gagal mengambil dokumen dari Elasticsearch: %v", err)
+		prev = nil
+	}
+	assignDocumentIDs(prev, finalList)
+
+	if s.Notifier != nil {
+		// Kegagalan notifikasi tidak menggagalkan siklus; temuan yang belum terkirim dicoba lagi nanti
+		if err := s.Notifier.Notify(ctx, finalList); err != nil {
+			log.Printf("[ERROR] Notifikasi - Gagal mengirim sebagian notifikasi: %v", err)
+		}
+	}
+
+	var ops []client.BulkOperation
+	snapshot, isSnapshot := s.Sink.(SnapshotSink)
+	if isSnapshot {
+		// Snapshot dibangun dari nol, sehingga tidak perlu menghapus dokumen lama
+		index, err := snapshot.BeginSnapshot(ctx)
+		if err != nil {
+			return &StageError{Stage: StageSink, Err: err}
+		}
+		report.Index = index
+		log.Printf("[INFO] Elasticsearch - Membangun snapshot di index %s", index)
+	} else {
+		// --- Sinkronisasi: hapus data yang sudah tidak ada di OCS/AD ---
+		known := make(map[string]struct{}, len(finalList))
+		for _, row := range finalList {
+			known[row.DocumentID()] = struct{}{}
+		}
+		for id := range prev {
+			if _, ok := known[id]; !ok {
+				ops = append(ops, client.BulkOperation{Action: "delete", DocumentID: id})
+			}
+		}
+	}
+
+	// --- Index/update data yang berubah ---
+	for i := range finalList {
+		row := &finalList[i]
+		row.ContentHash = row.ComputeContentHash()
+		// Snapshot baru selalu berisi semua dokumen
+		if old, ok := prev[row.DocumentID()]; ok && !isSnapshot && old.ContentHash == row.ContentHash {
+			report.Unchanged++
+			continue
+		}
+		body, err := json.Marshal(row)
+		if err != nil {
+			log.Printf("[ERROR] Gagal encode document %s: %v", row.ComputerName, err)
+			report.Failed++
+			continue
+		}
+		ops = append(ops, client.BulkOperation{Action: "index", DocumentID: row.DocumentID(), Body: body})
+	}
+
+	bulkRes, err := s.Sink.Bulk(ctx, ops)
+	report.Indexed = bulkRes.Indexed
+	report.Deleted = bulkRes.Deleted
+	report.Failed += bulkRes.Failed
+	for _, itemErr := range bulkRes.Errors {
+		log.Printf("[ERROR] Elasticsearch - %v", itemErr)
+	}
+	if isSnapshot && err == nil && report.Failed > 0 {
+		// Snapshot yang tidak lengkap tidak boleh diaktifkan
+		err = fmt.Errorf("%d dokumen gagal diindex ke snapshot", report.Failed)
+	}
+	if isSnapshot && err == nil && bulkRes.Indexed != len(ops) {
+		// Setiap operasi harus terkonfirmasi terindex sebelum alias dipindahkan dan generasi lama dihapus
+		err = fmt.Errorf("hanya %d dari %d dokumen terkonfirmasi terindex ke snapshot", bulkRes.Indexed, len(ops))
+	}
+	if !isSnapshot && err == nil && len(ops) > 0 && bulkRes.Failed == len(ops) {
+		// Tidak ada satu pun operasi yang tersimpan: Elasticsearch dianggap tidak tersedia
+		err = fmt.Errorf("semua %d operasi gagal ditulis ke Elasticsearch", len(ops))
+	}
+	if isSnapshot && err == nil {
+		err = snapshot.CommitSnapshot(ctx)
+	}
+	if err != nil {
+		if isSnapshot {
+			// Context siklus bisa saja sudah dibatalkan, pembersihan memakai context terpisah
+			abortCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if abortErr := snapshot.AbortSnapshot(abortCtx); abortErr != nil {
+				log.Printf("[ERROR] Elasticsearch - Gagal menghapus snapshot yang dibatalkan: %v", abortErr)
+			}
+			cancel()
+		}
+		return &StageError{Stage: StageSink, Err: err}
+	}
+	log.Printf("[INFO] Elasticsearch - Hapus data lama, Total: %d", bulkRes.Deleted)
+	log.Printf("[INFO] Elasticsearch - Indexing selesai. Sukses: %d, Tidak berubah: %d, Gagal: %d, Request: %d", bulkRes.Indexed, report.Unchanged, bulkRes.Failed, bulkRes.Requests)
+
+	// --- Daftar perubahan inventaris ---
+	// Tanpa data sebelumnya (index baru atau gagal dibaca) semua baris akan terlihat "added", sehingga dilewati
+	if s.Events != nil && len(prev) > 0 {
+		events := detectChanges(prev, finalList, time.Now())
+		report.Changes = len(events)
+		if err := s.Events.Publish(ctx, events); err != nil {
+			log.Printf("[ERROR] Gagal mengirim event perubahan: %v", err)
+		}
+	}
+
+	if s.History != nil {
+		// Kegagalan riwayat tidak menggagalkan siklus; snapshot hari ini dicoba lagi di siklus berikutnya
+		histRes, err := s.History.Record(ctx, finalList)
+		report.History = histRes.Indexed
+		if err != nil {
+			log.Printf("[ERROR] Elasticsearch - Gagal menulis riwayat harian: %v", err)
+		} else if histRes.Indexed > 0 || histRes.Failed > 0 {
+			log.Printf("[INFO] Elasticsearch - Riwayat harian ditulis. Sukses: %d, Gagal: %d", histRes.Indexed, histRes.Failed)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,343 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"ocs-ad-inventorymanagement/client"
+	"ocs-ad-inventorymanagement/parser"
+)
+
+// fakeAD adalah sumber AD statis
+type fakeAD struct {
+	rows []parser.ComputerReportRow
+	err  error
+}
+
+func (f *fakeAD) ListComputers(context.Context) ([]parser.ComputerReportRow, error) {
+	return f.rows, f.err
+}
+
+// fakeOCS adalah sumber OCS statis
+type fakeOCS struct {
+	rows []parser.OCSComputerRow
+	err  error
+}
+
+func (f *fakeOCS) ListComputersFunc(_ context.Context, fn func(parser.OCSComputerRow) error) error {
+	if f.err != nil {
+		return f.err
+	}
+	for _, row := range f.rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fakeSink menyimpan dokumen di memori dan mencatat operasi Bulk terakhir
+type fakeSink struct {
+	docs    map[string]parser.FinalComputerRow
+	fail    map[string]bool // Document ID yang selalu gagal ditulis
+	bulkErr error
+	ops     []client.BulkOperation
+}
+
+func newFakeSink() *fakeSink {
+	return &fakeSink{docs: make(map[string]parser.FinalComputerRow), fail: make(map[string]bool)}
+}
+
+func (f *fakeSink) ListDocuments(context.Context) (map[string]parser.FinalComputerRow, error) {
+	docs := make(map[string]parser.FinalComputerRow, len(f.docs))
+	for id, doc := range f.docs {
+		docs[id] = doc
+	}
+	return docs, nil
+}
+
+func (f *fakeSink) Bulk(_ context.Context, ops []client.BulkOperation) (client.BulkResult, error) {
+	f.ops = ops
+	var res client.BulkResult
+	if f.bulkErr != nil {
+		return res, f.bulkErr
+	}
+	for _, op := range ops {
+		if f.fail[op.DocumentID] {
+			res.Failed++
+			res.Errors = append(res.Errors, client.BulkItemError{Action: op.Action, DocumentID: op.DocumentID, Status: http.StatusBadRequest})
+			continue
+		}
+		if op.Action == "delete" {
+			delete(f.docs, op.DocumentID)
+			res.Deleted++
+			continue
+		}
+		var doc parser.FinalComputerRow
+		if err := json.Unmarshal(op.Body, &doc); err != nil {
+			return res, err
+		}
+		f.docs[op.DocumentID] = doc
+		res.Indexed++
+	}
+	res.Requests = 1
+	return res, nil
+}
+
+// opIDs mengembalikan "<action> <id>" dari operasi Bulk terakhir, terurut
+func (f *fakeSink) opIDs() []string {
+	var ids []string
+	for _, op := range f.ops {
+		ids = append(ids, op.Action+" "+op.DocumentID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func testSources() (*fakeAD, *fakeOCS) {
+	now := time.Now().UTC()
+	ad := &fakeAD{rows: []parser.ComputerReportRow{
+		{ComputerName: "PC-01", ComputerStatus: "enabled", LastLogonTime: now.AddDate(0, 0, -2)},
+		{ComputerName: "PC-02", ComputerStatus: "enabled", LastLogonTime: now.AddDate(0, 0, -3)},
+	}}
+	ocs := &fakeOCS{rows: []parser.OCSComputerRow{
+		{ID: 1, ComputerName: "PC-01", OCSStatus: "enabled", OCSLastCome: now.AddDate(0, 0, -1), OCSLastInventory: now.AddDate(0, 0, -1)},
+		{ID: 2, ComputerName: "PC-03", OCSStatus: "enabled", OCSLastCome: now.AddDate(0, 0, -1), OCSLastInventory: now.AddDate(0, 0, -1)},
+	}}
+	return ad, ocs
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRunOnceIndexesAndPrunes(t *testing.T) {
+	ad, ocs := testSources()
+	sink := newFakeSink()
+	sink.docs["OLD-PC"] = parser.FinalComputerRow{ComputerName: "OLD-PC", ExistsInAD: true}
+
+	report, err := NewSyncer(ad, ocs, sink).RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.ADFetched != 2 || report.OCSFetched != 2 || report.Merged != 3 {
+		t.Errorf("ADFetched=%d OCSFetched=%d Merged=%d, want 2/2/3", report.ADFetched, report.OCSFetched, report.Merged)
+	}
+	if report.Indexed != 3 || report.Deleted != 1 || report.Failed != 0 || report.Unchanged != 0 {
+		t.Errorf("Indexed=%d Deleted=%d Failed=%d Unchanged=%d, want 3/1/0/0", report.Indexed, report.Deleted, report.Failed, report.Unchanged)
+	}
+	want := []string{"delete OLD-PC", "index PC-01", "index PC-02", "index PC-03"}
+	if got := sink.opIDs(); !equalStrings(got, want) {
+		t.Errorf("ops = %v, want %v", got, want)
+	}
+	if _, ok := sink.docs["OLD-PC"]; ok {
+		t.Error("OLD-PC seharusnya dihapus dari sink")
+	}
+	if doc := sink.docs["PC-01"]; !doc.ExistsInAD || !doc.ExistsInOCS || doc.ContentHash == "" {
+		t.Errorf("PC-01 = %+v, want ada di AD dan OCS dengan content_hash", doc)
+	}
+}
+
+func TestRunOnceSkipsUnchanged(t *testing.T) {
+	ad, ocs := testSources()
+	sink := newFakeSink()
+	syncer := NewSyncer(ad, ocs, sink)
+	if _, err := syncer.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Siklus kedua tanpa perubahan tidak mengirim operasi apa pun
+	report, err := syncer.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Unchanged != 3 || report.Indexed != 0 || report.Deleted != 0 || len(sink.ops) != 0 {
+		t.Errorf("Unchanged=%d Indexed=%d Deleted=%d ops=%v, want 3/0/0 tanpa operasi", report.Unchanged, report.Indexed, report.Deleted, sink.opIDs())
+	}
+
+	// Hanya komputer yang berubah yang di-index ulang
+	ad.rows[1].ComputerStatus = "disabled"
+	report, err = syncer.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Unchanged != 2 || report.Indexed != 1 {
+		t.Errorf("Unchanged=%d Indexed=%d, want 2/1", report.Unchanged, report.Indexed)
+	}
+	if got, want := sink.opIDs(), []string{"index PC-02"}; !equalStrings(got, want) {
+		t.Errorf("ops = %v, want %v", got, want)
+	}
+}
+
+func TestRunOncePartialFailure(t *testing.T) {
+	ad, ocs := testSources()
+	sink := newFakeSink()
+	sink.fail["PC-02"] = true
+
+	// Sebagian item gagal: dilaporkan di Failed tanpa menggagalkan siklus
+	report, err := NewSyncer(ad, ocs, sink).RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Indexed != 2 || report.Failed != 1 {
+		t.Errorf("Indexed=%d Failed=%d, want 2/1", report.Indexed, report.Failed)
+	}
+}
+
+func TestRunOnceStageErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		setup func(ad *fakeAD, ocs *fakeOCS, sink *fakeSink)
+		stage string
+	}{
+		{
+			name:  "ad gagal",
+			setup: func(ad *fakeAD, _ *fakeOCS, _ *fakeSink) { ad.err = errors.New("ldap down") },
+			stage: StageAD,
+		},
+		{
+			name:  "ocs gagal",
+			setup: func(_ *fakeAD, ocs *fakeOCS, _ *fakeSink) { ocs.err = errors.New("mysql down") },
+			stage: StageOCS,
+		},
+		{
+			name:  "bulk gagal",
+			setup: func(_ *fakeAD, _ *fakeOCS, sink *fakeSink) { sink.bulkErr = errors.New("connection refused") },
+			stage: StageSink,
+		},
+		{
+			name: "semua item gagal",
+			setup: func(_ *fakeAD, _ *fakeOCS, sink *fakeSink) {
+				for _, id := range []string{"PC-01", "PC-02", "PC-03"} {
+					sink.fail[id] = true
+				}
+			},
+			stage: StageSink,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ad, ocs := testSources()
+			sink := newFakeSink()
+			tc.setup(ad, ocs, sink)
+
+			_, err := NewSyncer(ad, ocs, sink).RunOnce(context.Background())
+			var stageErr *StageError
+			if !errors.As(err, &stageErr) {
+				t.Fatalf("error = %v, want *StageError", err)
+			}
+			if stageErr.Stage != tc.stage {
+				t.Errorf("stage = %q, want %q", stageErr.Stage, tc.stage)
+			}
+		})
+	}
+}
+
+// fakeEvents mencatat ChangeEvent yang dipublikasikan
+type fakeEvents struct {
+	events []ChangeEvent
+}
+
+func (f *fakeEvents) Publish(_ context.Context, events []ChangeEvent) error {
+	f.events = append(f.events, events...)
+	return nil
+}
+
+func TestRunOncePublishesOnlyChanges(t *testing.T) {
+	ad, ocs := testSources()
+	sink := newFakeSink()
+	events := &fakeEvents{}
+	syncer := NewSyncer(ad, ocs, sink)
+	syncer.Events = events
+
+	// Sink kosong: siklus pertama tidak menghasilkan event "added" untuk semua baris
+	if _, err := syncer.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(events.events) != 0 {
+		t.Fatalf("events = %+v, want tidak ada pada index kosong", events.events)
+	}
+
+	ad.rows[0].ComputerStatus = "disabled"
+	report, err := syncer.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Unchanged != 2 || report.Indexed != 1 || report.Changes != 1 {
+		t.Errorf("Unchanged=%d Indexed=%d Changes=%d, want 2/1/1", report.Unchanged, report.Indexed, report.Changes)
+	}
+	if len(events.events) != 1 || events.events[0].Type != EventBecameDisabled || events.events[0].DocumentID != "PC-01" {
+		t.Errorf("events = %+v, want became_disabled PC-01", events.events)
+	}
+}
+
+func TestRunOnceKeepsDocumentIDAcrossADMatch(t *testing.T) {
+	now := time.Now().UTC()
+	ad := &fakeAD{rows: []parser.ComputerReportRow{
+		{ComputerName: "PC-02", Domain: "corp", ComputerStatus: "enabled", LastLogonTime: now.AddDate(0, 0, -1)},
+	}}
+	ocs := &fakeOCS{rows: []parser.OCSComputerRow{
+		{ID: 1, ComputerName: "PC-01", OCSStatus: "enabled", OCSLastCome: now.AddDate(0, 0, -1), OCSLastInventory: now.AddDate(0, 0, -1)},
+	}}
+	sink := newFakeSink()
+	events := &fakeEvents{}
+	syncer := NewSyncer(ad, ocs, sink)
+	syncer.Events = events
+	ctx := context.Background()
+
+	// Siklus 1: PC-01 hanya ada di OCS, PC-02 hanya ada di AD domain corp
+	if _, err := syncer.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sink.opIDs(), []string{"index PC-01", "index PC-02@corp"}; !equalStrings(got, want) {
+		t.Fatalf("siklus 1: ops = %v, want %v", got, want)
+	}
+
+	// Siklus 2: PC-01 muncul di AD domain corp dan PC-02 muncul di OCS; ID dokumen tidak berubah
+	ad.rows = append(ad.rows, parser.ComputerReportRow{ComputerName: "PC-01", Domain: "corp", ComputerStatus: "enabled", LastLogonTime: now.AddDate(0, 0, -1)})
+	ocs.rows = append(ocs.rows, parser.OCSComputerRow{ID: 2, ComputerName: "PC-02", OCSStatus: "enabled", OCSLastCome: now.AddDate(0, 0, -1), OCSLastInventory: now.AddDate(0, 0, -1)})
+	report, err := syncer.RunOnce(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sink.opIDs(), []string{"index PC-01", "index PC-02@corp"}; !equalStrings(got, want) || report.Deleted != 0 {
+		t.Fatalf("siklus 2: ops = %v, Deleted = %d, want %v tanpa delete", got, report.Deleted, want)
+	}
+	if doc := sink.docs["PC-01"]; !doc.ExistsInAD || doc.ADDomain != "corp" {
+		t.Errorf("siklus 2: PC-01 = %+v, want cocok dengan AD corp", doc)
+	}
+
+	// Siklus 3: PC-01 hilang lagi dari AD
+	ad.rows = ad.rows[:1]
+	if _, err := syncer.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sink.opIDs(), []string{"index PC-01"}; !equalStrings(got, want) {
+		t.Fatalf("siklus 3: ops = %v, want %v", got, want)
+	}
+	if len(sink.docs) != 2 {
+		t.Errorf("siklus 3: dokumen = %v, want 2", sink.docs)
+	}
+
+	var got []string
+	for _, ev := range events.events {
+		got = append(got, ev.Type+" "+ev.Source+" "+ev.DocumentID)
+	}
+	sort.Strings(got)
+	want := []string{"appeared ad PC-01", "appeared ocs PC-02@corp", "disappeared ad PC-01"}
+	if !equalStrings(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
@@ -115,6 +115,13 @@ var FrontendHTML = `
     #successMsg { text-align: center; margin-bottom: 1rem; font-size: 1.125rem; color: var(--text-primary); }
     .font-bold { font-weight: 600; }
 
+    /* Candidate selection (nama ambigu) */
+    .ocs-candidates { width: 100%; max-height: 260px; overflow-y: auto; display: flex; flex-direction: column; gap: 0.5rem; }
+    .ocs-candidate { border: 1px solid var(--border-color); border-radius: 8px; padding: 0.6rem 0.75rem; display: flex; gap: 0.6rem; align-items: flex-start; cursor: pointer; font-size: 0.875rem; }
+    .ocs-candidate:has(input:checked) { border-color: var(--accent-purple); box-shadow: 0 0 0 2px rgba(147, 49, 142, 0.2); }
+    .ocs-candidate input { margin-top: 0.2rem; accent-color: var(--accent-purple); }
+    .ocs-candidate-meta { color: var(--text-secondary); font-size: 0.8rem; word-break: break-all; }
+
     /* Custom Error Modal */
     .error-modal-overlay { position: fixed; inset: 0; background: rgba(0, 0, 0, 0.6); display: flex; align-items: center; justify-content: center; z-index: 100; padding: 1rem; }
     .error-modal-box { background: var(--bg-card); border: 1px solid var(--border-color); border-radius: 1rem; padding: 2rem; text-align: center; width: 95%; max-width: 400px; box-shadow: 0 4px_12px rgba(0,0,0,0.1); }
@@ -163,6 +170,19 @@ var FrontendHTML = `
       </form>
     </div>
     
+    <div id="stepChoose" class="ocs-step hidden">
+      <div class="ocs-logo"><img src="` + base64LogoOCS + `" alt="OCS Logo"></div>
+      <div class="ocs-title">Select Computer</div>
+      <div class="ocs-delete-info">
+        More than one record matches <span class="font-bold" id="chooseName"></span>.<br>
+        Select the record to delete.
+      </div>
+      <form id="chooseForm" class="ocs-form">
+        <div id="candidateList" class="ocs-candidates"></div>
+        <button type="submit" class="ocs-btn">Delete Selected</button>
+      </form>
+    </div>
+
     <div id="stepSuccess" class="ocs-step hidden"></div>
   </div>
 
@@ -191,10 +211,17 @@ var FrontendHTML = `
           return url.searchParams.get(name);
         }
 
-        const compName = getQueryParam('name') || '';
+        // Komputer bisa dipilih lewat ?id=, ?deviceid= atau ?name=
+        const selector = {};
+        const qId = parseInt(getQueryParam('id') || '', 10);
+        if (qId > 0) selector.id = qId;
+        if (getQueryParam('deviceid')) selector.deviceid = getQueryParam('deviceid');
+        if (getQueryParam('name')) selector.name = getQueryParam('name');
+        const compName = selector.name || selector.deviceid || (selector.id ? 'ID ' + selector.id : '');
+        const missingParamMsg = 'Parameter ?id=, ?deviceid= atau ?name= wajib diisi di URL.';
         if(document.getElementById('compName')) document.getElementById('compName').textContent = compName;
         if (!compName) {
-          showError('Parameter ?name= wajib diisi di URL.');
+          showError(missingParamMsg);
           if(document.getElementById('stepLogin')) document.getElementById('stepLogin').style.display = 'none';
         }
 
@@ -207,7 +234,7 @@ var FrontendHTML = `
 
         // Step control
         function showStep(step) {
-          ['stepLogin', 'stepConfirm', 'stepSuccess'].forEach(id => {
+          ['stepLogin', 'stepConfirm', 'stepChoose', 'stepSuccess'].forEach(id => {
             const el = document.getElementById(id);
             if (el) el.classList.add('hidden');
           });
@@ -234,7 +261,7 @@ var FrontendHTML = `
         const loginForm = document.getElementById('loginForm');
         if (loginForm) loginForm.onsubmit = async function(e) {
           e.preventDefault();
-          if (!compName) return showError('Parameter ?name= wajib diisi di URL.');
+          if (!compName) return showError(missingParamMsg);
           const username = document.getElementById('username').value.trim();
           const password = document.getElementById('password').value;
           if (!username || !password) return showError('Username dan password wajib diisi.');
@@ -271,13 +298,23 @@ var FrontendHTML = `
             return;
           }
 
-          try {
-            let localJwtToken = '';
-            document.cookie.split(';').forEach(function(c) {
-              let [k,v] = c.trim().split('=');
-              if (k === 'ocsjwt') localJwtToken = v;
-            });
+          await deleteComputer(selector);
+        };
+
+        // Baca token dari cookie; kosong jika sesi habis
+        function readJwtCookie() {
+          let localJwtToken = '';
+          document.cookie.split(';').forEach(function(c) {
+            let [k,v] = c.trim().split('=');
+            if (k === 'ocsjwt') localJwtToken = v;
+          });
+          return localJwtToken;
+        }
 
+        // Kirim permintaan hapus; jika nama cocok dengan beberapa record, tampilkan pilihan kandidat
+        async function deleteComputer(body) {
+          try {
+            const localJwtToken = readJwtCookie();
             if (!localJwtToken) {
                 showError('Session login tidak valid. Silakan login ulang.');
                 showStep('stepLogin');
@@ -290,17 +327,85 @@ var FrontendHTML = `
                 'Authorization': 'Bearer ' + localJwtToken,
                 'Content-Type': 'application/json'
               },
-              body: JSON.stringify({ name: compName })
+              body: JSON.stringify(body)
             });
             const data = await res.json();
+            if (res.status === 409 && data.candidates) {
+              showCandidates(data.candidates, data.truncated);
+              return;
+            }
             if (!res.ok) throw new Error(data.error || 'Delete failed');
 
-            if(document.getElementById('successMsg')) document.getElementById('successMsg').textContent = '"' + compName + '" Successfully Removed from OCS Inventory.';
+            const deleted = data.deleted || {};
+            const label = (deleted.name || compName) + (deleted.id ? ' (ID ' + deleted.id + ')' : '');
+            if(document.getElementById('successMsg')) document.getElementById('successMsg').textContent = '"' + label + '" Successfully Removed from OCS Inventory.';
             showStep('stepSuccess');
 
+            // Picu sinkronisasi agar Elasticsearch langsung ter-update tanpa menunggu jadwal
+            fetch(BASE_PATH + '/api/sync/run', {
+              method: 'POST',
+              headers: { 'Authorization': 'Bearer ' + localJwtToken }
+            }).catch(function() {});
+
           } catch (err) {
             showError(err.message);
           }
+        }
+
+        // Format tanggal dari API (RFC3339) ke waktu lokal browser
+        function formatDate(value) {
+          if (!value) return '-';
+          const d = new Date(value);
+          return isNaN(d.getTime()) ? value : d.toLocaleString();
+        }
+
+        // Tampilkan kandidat sebagai radio button; teks diisi via textContent agar aman dari HTML injection
+        function showCandidates(candidates, truncated) {
+          const list = document.getElementById('candidateList');
+          if (!list) return;
+          list.innerHTML = '';
+          candidates.forEach(function(cand, i) {
+            const item = document.createElement('label');
+            item.className = 'ocs-candidate';
+            const radio = document.createElement('input');
+            radio.type = 'radio';
+            radio.name = 'candidate';
+            radio.value = cand.id;
+            radio.required = true;
+            // Tidak ada yang dipilih otomatis: record terbaru (urutan pertama) paling mungkin masih dipakai
+            const info = document.createElement('div');
+            const title = document.createElement('div');
+            title.className = 'font-bold';
+            title.textContent = cand.name + ' (ID ' + cand.id + ')' + (i === 0 ? ' - newest' : '');
+            const meta = document.createElement('div');
+            meta.className = 'ocs-candidate-meta';
+            meta.textContent = 'Last inventory: ' + formatDate(cand.last_date) + ' | Last contact: ' + formatDate(cand.last_come) +
+              (cand.ip_address ? ' | IP: ' + cand.ip_address : '') + (cand.device_id ? ' | ' + cand.device_id : '');
+            info.appendChild(title);
+            info.appendChild(meta);
+            item.appendChild(radio);
+            item.appendChild(info);
+            list.appendChild(item);
+          });
+          if (truncated) {
+            const more = document.createElement('div');
+            more.className = 'ocs-candidate-meta';
+            more.textContent = 'Only the first ' + candidates.length + ' records are shown.';
+            list.appendChild(more);
+          }
+          if(document.getElementById('chooseName')) document.getElementById('chooseName').textContent = compName;
+          showStep('stepChoose');
+        }
+
+        // Choose form: hapus record yang dipilih berdasarkan ID
+        const chooseForm = document.getElementById('chooseForm');
+        if(chooseForm) chooseForm.onsubmit = async function(e) {
+          e.preventDefault();
+          if (!enforceStep('stepConfirm')) return;
+          const checked = document.querySelector('input[name="candidate"]:checked');
+          if (!checked) return showError('Pilih salah satu record.');
+          // Kriteria awal tetap dikirim agar ID yang dipilih harus cocok dengan nama/deviceid di URL
+          await deleteComputer(Object.assign({}, selector, { id: parseInt(checked.value, 10) }));
         };
 
         // Initializer